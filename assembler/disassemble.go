@@ -0,0 +1,72 @@
+package assembler
+
+import (
+	"fmt"
+
+	"github.com/martbul/instructions"
+	"github.com/martbul/registers"
+)
+
+// Disassemble decodes machine code into one line of assembly per instruction,
+// written in the syntax Assemble accepts
+func Disassemble(code []byte) ([]string, error) {
+	var lines []string
+	for address := 0; address < len(code); {
+		line, size, err := DisassembleInstruction(code[address:])
+		if err != nil {
+			return lines, fmt.Errorf("0x%04X: %w", address, err)
+		}
+		lines = append(lines, line)
+		address += size
+	}
+	return lines, nil
+}
+
+// DisassembleInstruction decodes the instruction at the start of code and
+// returns it together with its size in bytes
+func DisassembleInstruction(code []byte) (string, int, error) {
+	if len(code) == 0 {
+		return "", 0, fmt.Errorf("no instruction")
+	}
+	metadata, exists := instructions.GetInstructionByOpcode(code[0])
+	if !exists {
+		return "", 0, fmt.Errorf("unknown opcode 0x%02X", code[0])
+	}
+	if len(code) < int(metadata.Size) {
+		return "", 0, fmt.Errorf("%s needs %d bytes, only %d left", metadata.Instruction, metadata.Size, len(code))
+	}
+
+	line := metadata.Mnemonic
+	offset := 1
+	for i, kind := range instructions.Operands[metadata.Type] {
+		size := instructions.OperandSize(metadata.Type, kind)
+		value := int(code[offset])
+		if size == 2 {
+			value = value<<8 | int(code[offset+1])
+		}
+		offset += size
+
+		var operand string
+		switch kind {
+		case instructions.OperandRegister, instructions.OperandRegisterPointer:
+			if value >= len(registers.Registers) {
+				return "", 0, fmt.Errorf("%s: unknown register 0x%02X", metadata.Instruction, value)
+			}
+			operand = registers.Registers[value]
+			if kind == instructions.OperandRegisterPointer {
+				operand = "&" + operand
+			}
+		case instructions.OperandAddress:
+			operand = fmt.Sprintf("&%04X", value)
+		case instructions.OperandLiteral:
+			operand = fmt.Sprintf("$%0*X", size*2, value)
+		}
+
+		if i == 0 {
+			line += " " + operand
+		} else {
+			line += ", " + operand
+		}
+	}
+	return line, offset, nil
+}
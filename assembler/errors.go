@@ -0,0 +1,75 @@
+package assembler
+
+import (
+	"strings"
+
+	"github.com/martbul/assembler/parser"
+)
+
+// Severity tells whether a diagnostic stops the assembly or is only a warning.
+// Notes only appear attached to another diagnostic.
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityNote
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityNote:
+		return "note"
+	}
+	return "error"
+}
+
+// Diagnostic is a single message produced while assembling a program
+type Diagnostic struct {
+	Severity Severity
+	Pos      parser.Position
+	Message  string
+	Source   string       // the offending source line
+	Notes    []Diagnostic // related places, such as the macro invocation the error came from
+}
+
+// String formats the diagnostic as "file:line:col: error: message" followed
+// by the source line and a caret under the column, then the notes
+func (d Diagnostic) String() string {
+	var b strings.Builder
+	if d.Pos.Line > 0 {
+		b.WriteString(d.Pos.String() + ": ")
+	}
+	b.WriteString(d.Severity.String() + ": " + d.Message)
+	if d.Source != "" {
+		b.WriteString("\n    " + d.Source + "\n    ")
+		for i := 0; i < d.Pos.Column-1 && i < len(d.Source); i++ {
+			if d.Source[i] == '\t' {
+				b.WriteByte('\t')
+			} else {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteByte('^')
+	}
+	for _, note := range d.Notes {
+		b.WriteString("\n" + note.String())
+	}
+	return b.String()
+}
+
+// Error is returned by Assemble when the program could not be assembled.
+// It carries every error diagnostic that was collected.
+type Error struct {
+	Diagnostics []Diagnostic
+}
+
+func (e *Error) Error() string {
+	lines := make([]string, 0, len(e.Diagnostics))
+	for _, d := range e.Diagnostics {
+		lines = append(lines, d.String())
+	}
+	return strings.Join(lines, "\n")
+}
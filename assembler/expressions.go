@@ -0,0 +1,138 @@
+package assembler
+
+import (
+	"github.com/martbul/assembler/parser"
+)
+
+// value evaluates an operand and truncates it to the given number of bits,
+// warning when the result doesn't fit
+func (a *assembly) value(node *parser.Node, bits int) int {
+	value := a.evaluate(node)
+	limit := 1 << bits
+	if value >= limit || value < -limit/2 {
+		a.warnAt(node, "value %d (0x%X) does not fit in %d bits and is truncated to 0x%0*X", value, value, bits, bits/4, value&(limit-1))
+	}
+	return value & (limit - 1)
+}
+
+// evaluate computes the value of an expression. Errors are reported and
+// evaluate to 0 so that the rest of the program is still checked.
+func (a *assembly) evaluate(node *parser.Node) int {
+	switch node.Type {
+	case parser.TypeMemoryReference:
+		// &[...] wraps the bracketed expression
+		if nested, ok := node.Value.(*parser.Node); ok {
+			return a.evaluate(a.positioned(nested, node))
+		}
+
+	case parser.TypeVariable:
+		return a.lookup(node.Value.(string), node)
+
+	case parser.TypeHexLiteral, parser.TypeAddress, parser.TypeDecimalLiteral,
+		parser.TypeBinaryLiteral, parser.TypeCharLiteral:
+		value, err := literal(node)
+		if err != nil {
+			a.errorAt(node, "%v", err)
+		}
+		return value
+
+	case parser.TypeUnaryOperation:
+		operation := node.Value.(map[string]interface{})
+		value := a.evaluate(a.positioned(operation["value"].(*parser.Node), node))
+		if operation["op"].(*parser.Node).Type == parser.TypeOpNot {
+			return ^value & 0xffff
+		}
+		return -value
+
+	case parser.TypeBinaryOperation:
+		operation := node.Value.(map[string]interface{})
+		left := a.evaluate(a.positioned(operation["a"].(*parser.Node), node))
+		right := a.evaluate(a.positioned(operation["b"].(*parser.Node), node))
+		return a.binary(node, operation["op"].(*parser.Node).Type, left, right)
+
+	case parser.TypeSquareBracketExpr, parser.TypeBracketedExpr:
+		a.errorAt(node, "malformed expression, operators and values must alternate")
+		return 0
+	}
+
+	a.errorAt(node, "unsupported operand %s", node.Type)
+	return 0
+}
+
+func (a *assembly) binary(node *parser.Node, op parser.NodeType, left, right int) int {
+	switch op {
+	case parser.TypeOpPlus:
+		return left + right
+	case parser.TypeOpMinus:
+		return left - right
+	case parser.TypeOpMultiply:
+		return left * right
+	case parser.TypeOpDivide, parser.TypeOpModulo:
+		if right == 0 {
+			a.errorAt(node, "division by zero")
+			return 0
+		}
+		if op == parser.TypeOpDivide {
+			return left / right
+		}
+		return left % right
+	case parser.TypeOpShiftLeft, parser.TypeOpShiftRight:
+		if right < 0 || right > 31 {
+			a.errorAt(node, "shift by %d is out of range", right)
+			return 0
+		}
+		if op == parser.TypeOpShiftLeft {
+			return left << right
+		}
+		return left >> right
+	case parser.TypeOpAnd:
+		return left & right
+	case parser.TypeOpOr:
+		return left | right
+	case parser.TypeOpXor:
+		return left ^ right
+	}
+	a.errorAt(node, "unknown operator %s", op)
+	return 0
+}
+
+// lookup resolves a label, data block or constant. Constants are evaluated
+// on first use, a constant that ends up depending on itself is an error.
+func (a *assembly) lookup(name string, node *parser.Node) int {
+	if value, exists := a.symbols[name]; exists {
+		return value
+	}
+
+	c, exists := a.constants[name]
+	if !exists {
+		a.errorAt(node, "label %q wasn't resolved", name)
+		return 0
+	}
+	if c.resolving {
+		a.errorAt(node, "constant %q is defined in terms of itself", name)
+		return 0
+	}
+
+	c.resolving = true
+	a.constants[name] = c
+	statement := a.pos
+	a.pos = c.pos
+	value := a.value(a.positioned(c.value, &parser.Node{Pos: c.pos}), 16)
+	a.pos = statement
+	c.resolving = false
+	a.constants[name] = c
+
+	a.symbols[name] = value
+	return value
+}
+
+// positioned gives a node from inside an expression the position of the
+// operand it belongs to, expression nodes don't carry their own
+func (a *assembly) positioned(node, parent *parser.Node) *parser.Node {
+	if node.Pos.Line != 0 || parent.Pos.Line == 0 {
+		return node
+	}
+	copied := *node
+	copied.Pos = parent.Pos
+	return &copied
+}
@@ -1,262 +1,419 @@
 package assembler
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"strconv"
+	"sort"
 	"strings"
 
 	"github.com/martbul/assembler/parser"
 	"github.com/martbul/instructions"
+	"github.com/martbul/memory"
 	"github.com/martbul/registers"
 )
 
-func AssembleProgram(program string) {
-	parsedNodes, err := parser.ParseProgram(program)
-	for _, n := range parsedNodes {
-		parser.PrettyPrintNode(n)
+// Options controls the optional behaviour of Assemble
+type Options struct {
+	Filename     string    // name used in diagnostics, included files are looked up next to it
+	IncludePaths []string  // directories searched by include and incbin after the including file's own
+	Debug        bool      // print the parsed AST, the machine code and the symbol table
+	Output       io.Writer // where debug output goes, os.Stdout when nil
+}
+
+// Result is an assembled program
+type Result struct {
+	Code        []byte         // image of the whole program, Code[0] belongs at Origin
+	Sections    []Section      // the parts of Code that hold code or data, by address
+	Symbols     map[string]int // labels, data blocks and constants
+	Origin      int            // address of the first byte of Code
+	Diagnostics []Diagnostic   // warnings collected while assembling
+}
+
+// Load copies the sections into memory. The gaps between them are left
+// untouched, so a program can sit around memory used for something else.
+func (r *Result) Load(dv *memory.DataView) error {
+	buffer := dv.GetBuffer()
+	for _, s := range r.Sections {
+		if s.Address+len(s.Code) > len(buffer) {
+			return fmt.Errorf("%d bytes at 0x%04X do not fit in %d bytes of memory", len(s.Code), s.Address, len(buffer))
+		}
+		copy(buffer[s.Address:], s.Code)
 	}
+	return nil
+}
 
-	if err != nil {
-		fmt.Println("here")
-		fmt.Fprintf(os.Stderr, "Error parsing program: %v\n", err)
-		os.Exit(1)
+// AssembleProgram assembles the program and prints the AST, the machine code and the labels
+func AssembleProgram(program string) {
+	if _, err := Assemble(program, Options{Debug: true}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error assembling program:\n%v\n", err)
 	}
+}
 
-	// Initialize machine code array and labels map
-	machineCode := []byte{}
-	symbolicNames := make(map[string]int)
-	currentAddress := 0
+// Assemble turns assembly source into machine code. On failure the returned
+// error is an *Error holding every diagnostic that was collected.
+func Assemble(program string, options ...Options) (*Result, error) {
+	var opts Options
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	out := opts.Output
+	if out == nil {
+		out = os.Stdout
+	}
 
-	// First pass: resolve labels
-	for _, node := range parsedNodes {
-		if node.Type == "LABEL" {
-			labelName, ok := node.Value.(map[string]interface{})["label"].(string)
-			if !ok {
-				fmt.Fprintf(os.Stderr, "Invalid label format\n")
-				os.Exit(1)
-			}
-			symbolicNames[labelName] = currentAddress
-		} else if node.Type == "CONSTANT" {
-
-			//WARN: NO IDEA WHAT TO DO
-			//INFO: THE JS VERSION:
-			// symbolicNames[node.value.name] = parseInt(node.value.value.value,16) & 0xffff
-			constantValue := node.Value.(map[string]interface{})
-			constantName := constantValue["name"].(string)
-
-			// Extract the value from the nested structure
-			valueMap := constantValue["value"].(map[string]interface{})
-			hexValue := valueMap["value"].(string)
-
-			// Parse the hex value and mask to 16 bits (& 0xffff)
-			var intValue int64
-			intValue, err := strconv.ParseInt(hexValue, 16, 32)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error parsing constant value: %v\n", err)
-				os.Exit(1)
-			}
+	a := &assembly{
+		symbols:   make(map[string]int),
+		constants: make(map[string]constant),
+		layout:    make(map[*parser.Node]placement),
+		sections:  []*section{{}}, // code before the first org starts at 0
+		files:     map[string][]string{opts.Filename: strings.Split(program, "\n")},
+	}
 
-			// Store the value in the symbolicNames map
-			symbolicNames[constantName] = int(intValue & 0xffff)
-
-		} else if node.Type == "DATA_DECLARATION" {
-			// Process data declaration
-			dataValue := node.Value.(map[string]interface{})
-			dataName := dataValue["name"].(string)
-			// Store the address where this data begins
-			symbolicNames[dataName] = currentAddress
-
-			// Increment address based on data size and number of values
-			dataSize := dataValue["size"].(int)
-			dataValues := dataValue["values"].([]string)
-			bytesPerValue := dataSize / 8 // 8-bit data = 1 byte, 16-bit data = 2 bytes
-			currentAddress += len(dataValues) * bytesPerValue
-		} else {
-			// Must be an instruction
-			instrValue, ok := node.Value.(map[string]interface{})
-			if !ok {
-				fmt.Fprintf(os.Stderr, "Invalid instruction format\n")
-				os.Exit(1)
-			}
-			instrType := instrValue["instruction"].(string)
-			metadata, exists := instructions.GetInstructionByName(instrType)
-			if !exists {
-				fmt.Fprintf(os.Stderr, "Unknown instruction: %s\n", instrType)
-				os.Exit(1)
+	parsedNodes, err := parser.ParseProgram(program, parser.Options{
+		Filename:     opts.Filename,
+		IncludePaths: opts.IncludePaths,
+		ReadFile:     a.readFile,
+	})
+	if err != nil {
+		var errs parser.ErrorList
+		if !errors.As(err, &errs) {
+			return nil, &Error{Diagnostics: []Diagnostic{{Severity: SeverityError, Message: err.Error()}}}
+		}
+		for _, e := range errs {
+			a.report(SeverityError, e.Pos, "%s", e.Message)
+			d := &a.diagnostics[len(a.diagnostics)-1]
+			for _, note := range e.Notes {
+				d.Notes = append(d.Notes, a.diagnostic(SeverityNote, note.Pos, note.Message))
 			}
-			currentAddress += int(metadata.Size)
+		}
+		return nil, &Error{Diagnostics: a.diagnostics}
+	}
+	if opts.Debug {
+		for _, n := range parsedNodes {
+			printNode(out, n)
 		}
 	}
 
-	// Second pass: encode instructions
-	for _, node := range parsedNodes {
-		// Skip labels in second pass
-		if node.Type == "LABEL" || node.Type == "CONSTANT" {
-			continue
-		} else if node.Type == "DATA_DECLARATION" {
-			// Encode data values
-			dataValue := node.Value.(map[string]interface{})
-			dataSize := dataValue["size"].(int)
-			dataValues := dataValue["values"].([]string)
+	a.resolveSymbols(parsedNodes)
+	if a.errorCount == 0 {
+		a.encode(parsedNodes)
+	}
+	var result Result
+	if a.errorCount == 0 {
+		result.Sections, result.Code, result.Origin = a.link()
+	}
+	if a.errorCount > 0 {
+		return nil, &Error{Diagnostics: a.diagnostics}
+	}
 
-			for _, valueStr := range dataValues {
-				var hexVal int
-				fmt.Sscanf(valueStr, "%X", &hexVal)
+	result.Symbols = a.symbols
+	result.Diagnostics = a.diagnostics
+	if opts.Debug {
+		printResult(out, &result)
+	}
+	return &result, nil
+}
 
-				if dataSize == 8 {
-					// For 8-bit data, just encode the low byte
-					machineCode = append(machineCode, byte(hexVal&0xFF))
-				} else {
-					// For 16-bit data, encode both high and low bytes
-					highByte := byte((hexVal & 0xFF00) >> 8)
-					lowByte := byte(hexVal & 0x00FF)
-					machineCode = append(machineCode, highByte, lowByte)
-				}
-			}
+// assembly holds the state shared by the two passes
+type assembly struct {
+	sections      []*section
+	layout        map[*parser.Node]placement // where each directive moves the location counter
+	symbols       map[string]int
+	constants     map[string]constant
+	constantOrder []string
+	diagnostics   []Diagnostic
+	errorCount    int
+	files         map[string][]string // source lines of every file, quoted in diagnostics
+	pos           parser.Position     // statement being assembled
+}
+
+// constant is a constant whose value is evaluated the first time it's used,
+// so it can refer to labels defined after it
+type constant struct {
+	value     *parser.Node
+	pos       parser.Position
+	resolving bool
+}
+
+// report records a diagnostic at pos
+func (a *assembly) report(severity Severity, pos parser.Position, format string, args ...interface{}) {
+	a.diagnostics = append(a.diagnostics, a.diagnostic(severity, pos, fmt.Sprintf(format, args...)))
+	if severity == SeverityError {
+		a.errorCount++
+	}
+}
+
+// readFile reads an included file and keeps its lines for diagnostics
+func (a *assembly) readFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		a.files[path] = strings.Split(string(data), "\n")
+	}
+	return data, err
+}
+
+// diagnostic builds a diagnostic with its source line. A position inside a
+// macro, rept block or included file gets a note for every invocation or
+// include it came through.
+func (a *assembly) diagnostic(severity Severity, pos parser.Position, message string) Diagnostic {
+	d := Diagnostic{Severity: severity, Pos: pos, Message: message}
+	if lines := a.files[pos.File]; pos.Line > 0 && pos.Line <= len(lines) {
+		d.Source = strings.TrimRight(lines[pos.Line-1], "\r")
+	}
+	for e := pos.Expansion; e != nil; e = e.Pos.Expansion {
+		// a macro that invokes itself repeats the same place, it's shown once
+		repeats := 1
+		for next := e.Pos.Expansion; next != nil && next.Macro == e.Macro && next.Include == e.Include &&
+			next.Pos.File == e.Pos.File && next.Pos.Line == e.Pos.Line && next.Pos.Column == e.Pos.Column; next = e.Pos.Expansion {
+			e = next
+			repeats++
+		}
+		message := "in this rept block"
+		switch {
+		case e.Macro != "":
+			message = fmt.Sprintf("in the expansion of macro %s", e.Macro)
+		case e.Include != "":
+			message = fmt.Sprintf("%s is included here", e.Include)
+		}
+		if repeats > 1 {
+			message += fmt.Sprintf(" (%d times)", repeats)
 		}
+		at := e.Pos
+		at.Expansion = nil // the loop adds the rest of the chain
+		d.Notes = append(d.Notes, a.diagnostic(SeverityNote, at, message))
+	}
+	return d
+}
 
-		instrValue := node.Value.(map[string]interface{})
-		instrType := instrValue["instruction"].(string)
-		metadata, _ := instructions.GetInstructionByName(instrType)
-		// Add opcode
-		machineCode = append(machineCode, metadata.Opcode)
+// errorf reports an error in the statement being assembled
+func (a *assembly) errorf(format string, args ...interface{}) {
+	a.report(SeverityError, a.pos, format, args...)
+}
 
-		// Get arguments
-		args := instrValue["args"].([]*parser.Node)
+// errorAt reports an error at an operand, or at its statement if the operand has no position
+func (a *assembly) errorAt(node *parser.Node, format string, args ...interface{}) {
+	pos := node.Pos
+	if pos.Line == 0 {
+		pos = a.pos
+	}
+	a.report(SeverityError, pos, format, args...)
+}
 
-		// Encode arguments based on instruction type
-		switch metadata.Type {
-		case instructions.LitReg, instructions.MemReg:
-			encodeLitOrMem(&machineCode, args[0], symbolicNames)
-			encodeReg(&machineCode, args[1])
+// warnAt reports a warning at an operand, or at its statement if the operand has no position
+func (a *assembly) warnAt(node *parser.Node, format string, args ...interface{}) {
+	pos := node.Pos
+	if pos.Line == 0 {
+		pos = a.pos
+	}
+	a.report(SeverityWarning, pos, format, args...)
+}
+
+// define adds a label or data block to the symbol table
+func (a *assembly) define(name string, address int) {
+	if _, exists := a.symbols[name]; exists {
+		a.errorf("%q is already defined", name)
+		return
+	}
+	if _, exists := a.constants[name]; exists {
+		a.errorf("%q is already defined as a constant", name)
+		return
+	}
+	a.symbols[name] = address
+}
+
+// First pass: resolve labels, constants and data blocks to addresses
+func (a *assembly) resolveSymbols(parsedNodes []*parser.Node) {
+	currentAddress := 0
+	overflowed := false
 
-		case instructions.RegLit8:
-			encodeReg(&machineCode, args[0])
-			encodeLit8(&machineCode, args[1], symbolicNames)
+	for _, node := range parsedNodes {
+		a.pos = node.Pos
+		value, _ := node.Value.(map[string]interface{})
+		if value == nil {
+			a.errorf("invalid %s node", node.Type)
+			continue
+		}
 
-		case instructions.RegLit, instructions.RegMem:
-			encodeReg(&machineCode, args[0])
-			encodeLitOrMem(&machineCode, args[1], symbolicNames)
+		switch node.Type {
+		case parser.TypeLabel:
+			a.define(value["label"].(string), currentAddress)
 
-		case instructions.LitMem:
-			encodeLitOrMem(&machineCode, args[0], symbolicNames)
-			encodeLitOrMem(&machineCode, args[1], symbolicNames)
+		case parser.TypeConstant:
+			name := value["name"].(string)
+			if _, exists := a.constants[name]; exists {
+				a.errorf("constant %q is already defined", name)
+				continue
+			}
+			if _, exists := a.symbols[name]; exists {
+				a.errorf("%q is already defined", name)
+				continue
+			}
+			a.constants[name] = constant{value: value["value"].(*parser.Node), pos: node.Pos}
+			a.constantOrder = append(a.constantOrder, name)
 
-		case instructions.RegReg, instructions.RegPtrReg:
-			encodeReg(&machineCode, args[0])
-			encodeReg(&machineCode, args[1])
+		case parser.TypeData:
+			a.define(value["name"].(string), currentAddress)
+			currentAddress += a.dataSize(value)
 
-		case instructions.LitOffReg:
-			encodeLitOrMem(&machineCode, args[0], symbolicNames)
-			encodeReg(&machineCode, args[1])
-			encodeReg(&machineCode, args[2])
+		case parser.TypeDirective:
+			currentAddress = a.directive(node, currentAddress)
 
-		case instructions.SingleReg:
-			encodeReg(&machineCode, args[0])
+		case parser.TypeIncbin:
+			currentAddress += len(value["data"].([]byte))
 
-		case instructions.SingleLit:
-			encodeLitOrMem(&machineCode, args[0], symbolicNames)
+		default:
+			instrType, _ := value["instruction"].(string)
+			metadata, exists := instructions.GetInstructionByName(instrType)
+			if !exists {
+				a.errorf("unknown instruction %q", instrType)
+				continue
+			}
+			currentAddress += int(metadata.Size)
 		}
-	}
 
-	// Print the resulting machine code
-	fmt.Println("Machine code:")
-	for i, b := range machineCode {
-		fmt.Printf("%02X ", b)
-		if (i+1)%8 == 0 {
-			fmt.Println()
+		if currentAddress > addressSpace && !overflowed {
+			a.errorf("code runs past the end of memory at 0x%04X", addressSpace-1)
+			overflowed = true
 		}
 	}
-	fmt.Println()
 
-	// Print resolved labels
-	fmt.Println("Labels:")
-	for label, addr := range symbolicNames {
-		fmt.Printf("%s: 0x%04X\n", label, addr)
+	// Every label is known now, so constants can be evaluated
+	for _, name := range a.constantOrder {
+		a.lookup(name, &parser.Node{Type: parser.TypeVariable, Value: name, Pos: a.constants[name].pos})
 	}
 }
 
-// encodeLit8 encodes an 8-bit literal
-func encodeLit8(machineCode *[]byte, node *parser.Node, labels map[string]int) {
-	var hexVal int
-
-	if node.Type == "VARIABLE" {
-		labelName := node.Value.(string)
-		addr, exists := labels[labelName]
-		if !exists {
-			fmt.Fprintf(os.Stderr, "Error: label '%s' wasn't resolved\n", labelName)
-			os.Exit(1)
+// dataSize returns the number of bytes a data declaration takes and reports
+// strings that can't be used in it
+func (a *assembly) dataSize(value map[string]interface{}) int {
+	bytesPerValue := value["size"].(int) / 8 // 8-bit data = 1 byte, 16-bit data = 2 bytes
+	size := 0
+	for _, v := range value["values"].([]*parser.Node) {
+		if v.Type != parser.TypeStringLiteral {
+			size += bytesPerValue
+			continue
+		}
+		if bytesPerValue != 1 {
+			a.errorAt(v, "strings can only be used in data8")
+			continue
 		}
-		hexVal = addr
-	} else {
-		valueStr := node.Value.(string)
-		if strings.HasPrefix(valueStr, "$") || strings.HasPrefix(valueStr, "&") {
-			valueStr = valueStr[1:]
+		chars, err := unquote(v.Value.(string))
+		if err != nil {
+			a.errorAt(v, "%v", err)
 		}
-		fmt.Sscanf(valueStr, "%X", &hexVal)
+		size += len(chars)
 	}
+	return size
+}
+
+// Second pass: encode data and instructions
+func (a *assembly) encode(parsedNodes []*parser.Node) {
+	for _, node := range parsedNodes {
+		a.pos = node.Pos
+		value := node.Value.(map[string]interface{})
+
+		switch node.Type {
+		case parser.TypeLabel, parser.TypeConstant:
+			continue
+
+		case parser.TypeDirective:
+			a.place(node)
+			continue
 
-	// Push just the low byte
-	lowByte := byte(hexVal & 0x00FF)
-	*machineCode = append(*machineCode, lowByte)
+		case parser.TypeIncbin:
+			a.emit(value["data"].([]byte)...)
+			continue
+
+		case parser.TypeData:
+			dataSize := value["size"].(int)
+			for _, v := range value["values"].([]*parser.Node) {
+				if v.Type == parser.TypeStringLiteral {
+					chars, _ := unquote(v.Value.(string)) // checked by dataSize
+					a.emit(chars...)
+					continue
+				}
+				data := a.value(v, dataSize)
+				if dataSize == 8 {
+					a.emit(byte(data))
+				} else {
+					a.emit(byte(data>>8), byte(data))
+				}
+			}
+			continue
+		}
+
+		instrType := value["instruction"].(string)
+		metadata, _ := instructions.GetInstructionByName(instrType)
+		args := value["args"].([]*parser.Node)
+		a.emit(metadata.Opcode)
+
+		for i, kind := range instructions.Operands[metadata.Type] {
+			switch {
+			case kind == instructions.OperandRegister || kind == instructions.OperandRegisterPointer:
+				a.encodeReg(args[i])
+			case instructions.OperandSize(metadata.Type, kind) == 1:
+				a.encodeLit8(args[i])
+			default:
+				a.encodeLitOrMem(args[i])
+			}
+		}
+	}
+}
+
+// encodeLit8 encodes an 8-bit literal
+func (a *assembly) encodeLit8(node *parser.Node) {
+	a.emit(byte(a.value(node, 8)))
 }
 
 // encodeReg encodes a register reference
-func encodeReg(machineCode *[]byte, node *parser.Node) {
+func (a *assembly) encodeReg(node *parser.Node) {
 	regName := strings.ToLower(node.Value.(string))
 	regCode, exists := registers.Map[regName]
 	if !exists {
-		fmt.Fprintf(os.Stderr, "Error: unknown register '%s'\n", regName)
-		os.Exit(1)
+		a.errorAt(node, "unknown register %q", regName)
 	}
-	*machineCode = append(*machineCode, byte(regCode))
+	a.emit(byte(regCode))
 }
 
-// encodeLitOrMem encodes a literal or memory address
-func encodeLitOrMem(machineCode *[]byte, node *parser.Node, labels map[string]int) {
-	var hexVal int
-
-	// Handle MEMORY_REFERENCE type which contains a nested VARIABLE
-	if node.Type == "MEMORY_REFERENCE" {
-		if nestedNode, ok := node.Value.(map[string]interface{}); ok {
-			if nestedType, ok := nestedNode["type"].(string); ok && nestedType == "VARIABLE" {
-				if labelName, ok := nestedNode["value"].(string); ok {
-					addr, exists := labels[labelName]
-					if !exists {
-						fmt.Fprintf(os.Stderr, "Error: label '%s' wasn't resolved\n", labelName)
-						os.Exit(1)
-					}
-					hexVal = addr
-					goto encodeValue // Skip further processing
-				}
+// encodeLitOrMem encodes a 16-bit literal or memory address
+func (a *assembly) encodeLitOrMem(node *parser.Node) {
+	value := a.value(node, 16)
+	a.emit(byte(value>>8), byte(value))
+}
+
+func printNode(out io.Writer, node *parser.Node) {
+	jsonData, err := json.MarshalIndent(node, "", "  ")
+	if err != nil {
+		fmt.Fprintln(out, "error marshaling node:", err)
+		return
+	}
+	fmt.Fprintln(out, string(jsonData))
+}
+
+func printResult(out io.Writer, result *Result) {
+	fmt.Fprintln(out, "Machine code:")
+	for _, s := range result.Sections {
+		fmt.Fprintf(out, "0x%04X:\n", s.Address)
+		for i, b := range s.Code {
+			fmt.Fprintf(out, "%02X ", b)
+			if (i+1)%8 == 0 {
+				fmt.Fprintln(out)
 			}
 		}
+		fmt.Fprintln(out)
 	}
 
-	// Check if this is a direct VARIABLE (label reference)
-	if node.Type == "VARIABLE" {
-		labelName := node.Value.(string)
-		addr, exists := labels[labelName]
-		if !exists {
-			fmt.Fprintf(os.Stderr, "Error: label '%s' wasn't resolved\n", labelName)
-			os.Exit(1)
-		}
-		hexVal = addr
-	} else if node.Type == "HEX_LITERAL" || node.Type == "ADDRESS" {
-		// Must be a literal (e.g., "$0A", "&0050")
-		// Extract value from hex format and parse
-		valueStr := node.Value.(string)
-		// Remove "$" or "&" prefix if present
-		if strings.HasPrefix(valueStr, "$") || strings.HasPrefix(valueStr, "&") {
-			valueStr = valueStr[1:]
-		}
-		fmt.Sscanf(valueStr, "%X", &hexVal)
+	names := make([]string, 0, len(result.Symbols))
+	for name := range result.Symbols {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-encodeValue:
-	// Push high byte and low byte
-	highByte := byte((hexVal & 0xFF00) >> 8)
-	lowByte := byte(hexVal & 0x00FF)
-	*machineCode = append(*machineCode, highByte, lowByte)
+	fmt.Fprintln(out, "Labels:")
+	for _, name := range names {
+		fmt.Fprintf(out, "%s: 0x%04X\n", name, result.Symbols[name])
+	}
 }
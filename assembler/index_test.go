@@ -0,0 +1,35 @@
+package assembler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// BenchmarkAssemble10kLines assembles a generated program of 10k lines
+func BenchmarkAssemble10kLines(b *testing.B) {
+	var program strings.Builder
+	program.WriteString("constant step = $0002\n")
+	for i := 0; i < 10000; i += 10 {
+		fmt.Fprintf(&program, "block_%d:\n", i)
+		program.WriteString("mov $0001, r1\n")
+		program.WriteString("mov [!step], r2\n")
+		program.WriteString("add r1, r2\n")
+		program.WriteString("mov acc, &0100\n")
+		program.WriteString("mov &0100, r3\n")
+		program.WriteString("mov $10, &r3, r4\n")
+		program.WriteString("psh r4\n")
+		program.WriteString("pop r5\n")
+		fmt.Fprintf(&program, "jne $0000, &[!block_%d]\n", i)
+	}
+	program.WriteString("hlt\n")
+	source := program.String()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Assemble(source); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package assembler
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/martbul/instructions"
+	"github.com/martbul/registers"
+)
+
+// sampleValues are written for every address and literal operand. Besides the
+// edges, $0B10 and $0B01 are addresses whose digits also read as 0b binary.
+var sampleValues = []int{0x3010, 0x0000, 0x0001, 0x00FF, 0x0100, 0x0B10, 0x0B01, 0xC0DE, 0xFFFF}
+
+// TestInstructionTable checks that every opcode in instructions.Instructions
+// can be written in assembly, and that assembling, then disassembling and
+// assembling again gives back the same bytes.
+func TestInstructionTable(t *testing.T) {
+	opcodes := make(map[byte]string)
+	signatures := make(map[string]string)
+
+	for _, meta := range instructions.Instructions {
+		if other, exists := opcodes[meta.Opcode]; exists {
+			t.Errorf("%s: opcode 0x%02X is also used by %s", meta.Instruction, meta.Opcode, other)
+		}
+		opcodes[meta.Opcode] = meta.Instruction
+
+		operands, exists := instructions.Operands[meta.Type]
+		if !exists {
+			t.Errorf("%s: instruction type %d has no operand list", meta.Instruction, meta.Type)
+			continue
+		}
+
+		size := 1
+		for _, kind := range operands {
+			size += instructions.OperandSize(meta.Type, kind)
+		}
+		if size != int(meta.Size) {
+			t.Errorf("%s: operands take %d bytes but the size is %d", meta.Instruction, size, meta.Size)
+		}
+
+		signature := fmt.Sprint(meta.Mnemonic, operands)
+		if other, exists := signatures[signature]; exists {
+			t.Errorf("%s: written the same way as %s", meta.Instruction, other)
+		}
+		signatures[signature] = meta.Instruction
+
+		for _, value := range sampleValues {
+			source, code := sampleInstruction(meta.Mnemonic, meta.Type, operands, value)
+			if err := roundTrip(source, append([]byte{meta.Opcode}, code...)); err != nil {
+				t.Errorf("%s: %v", meta.Instruction, err)
+			}
+		}
+	}
+}
+
+// TestOperandPatterns writes every mnemonic with every combination of up to
+// three operand kinds. A combination has to assemble to the one opcode with
+// exactly those operands, or fail when there is none, so no opcode can be
+// written in a second way.
+func TestOperandPatterns(t *testing.T) {
+	kinds := []instructions.OperandKind{
+		instructions.OperandRegister, instructions.OperandRegisterPointer,
+		instructions.OperandAddress, instructions.OperandLiteral,
+	}
+	var patterns [][]instructions.OperandKind
+	for n := 0; n <= 3; n++ {
+		patterns = append(patterns, combinations(kinds, n)...)
+	}
+
+	for mnemonic, candidates := range instructions.InstructionsByMnemonic {
+		for _, pattern := range patterns {
+			var matching []instructions.MetaData
+			for _, meta := range candidates {
+				if slices.Equal(instructions.Operands[meta.Type], pattern) {
+					matching = append(matching, meta)
+				}
+			}
+
+			source, _ := sampleInstruction(mnemonic, 0, pattern, 0x10)
+			result, err := Assemble(source)
+			switch {
+			case len(matching) > 1:
+				t.Errorf("%q matches %s and %s", source, matching[0].Instruction, matching[1].Instruction)
+			case len(matching) == 1 && err != nil:
+				t.Errorf("%q does not assemble to %s: %v", source, matching[0].Instruction, err)
+			case len(matching) == 1 && result.Code[0] != matching[0].Opcode:
+				t.Errorf("%q assembled to opcode 0x%02X instead of %s", source, result.Code[0], matching[0].Instruction)
+			case len(matching) == 0 && err == nil:
+				t.Errorf("%q assembled to % X but no %s opcode takes these operands", source, result.Code, mnemonic)
+			}
+		}
+	}
+}
+
+// combinations returns every sequence of n kinds
+func combinations(kinds []instructions.OperandKind, n int) [][]instructions.OperandKind {
+	if n == 0 {
+		return [][]instructions.OperandKind{nil}
+	}
+	var all [][]instructions.OperandKind
+	for _, rest := range combinations(kinds, n-1) {
+		for _, kind := range kinds {
+			all = append(all, append(slices.Clone(rest), kind))
+		}
+	}
+	return all
+}
+
+// sampleInstruction writes the instruction with registers r1, r2, ... and value
+// for every address and literal. It returns the source and the operand bytes it
+// has to assemble to.
+func sampleInstruction(mnemonic string, t instructions.InstructionType, operands []instructions.OperandKind, value int) (string, []byte) {
+	line := mnemonic
+	var code []byte
+	for i, kind := range operands {
+		size := instructions.OperandSize(t, kind)
+		value := value & (1<<(size*8) - 1)
+
+		var operand string
+		switch kind {
+		case instructions.OperandRegister:
+			operand = fmt.Sprintf("r%d", i+1)
+			value = registers.Map[operand]
+		case instructions.OperandRegisterPointer:
+			operand = fmt.Sprintf("&r%d", i+1)
+			value = registers.Map[operand[1:]]
+		case instructions.OperandAddress:
+			operand = fmt.Sprintf("&%04X", value)
+		case instructions.OperandLiteral:
+			operand = fmt.Sprintf("$%0*X", size*2, value)
+		}
+		if size == 2 {
+			code = append(code, byte(value>>8))
+		}
+		code = append(code, byte(value))
+
+		if i == 0 {
+			line += " " + operand
+		} else {
+			line += ", " + operand
+		}
+	}
+	return line, code
+}
+
+func roundTrip(source string, want []byte) error {
+	first, err := Assemble(source)
+	if err != nil {
+		return fmt.Errorf("assembling %q: %w", source, err)
+	}
+	if !bytes.Equal(first.Code, want) {
+		return fmt.Errorf("%q assembled to % X, want % X", source, first.Code, want)
+	}
+
+	decoded, size, err := DisassembleInstruction(first.Code)
+	if err != nil {
+		return fmt.Errorf("disassembling % X: %w", first.Code, err)
+	}
+	if size != len(want) {
+		return fmt.Errorf("disassembled %d bytes of % X", size, first.Code)
+	}
+
+	second, err := Assemble(decoded)
+	if err != nil {
+		return fmt.Errorf("assembling disassembled %q: %w", decoded, err)
+	}
+	if !bytes.Equal(first.Code, second.Code) {
+		return fmt.Errorf("%q assembled to % X but its disassembly %q to % X", source, first.Code, decoded, second.Code)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package assembler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/martbul/assembler/parser"
+)
+
+// literal returns the value of a number or character literal
+func literal(node *parser.Node) (int, error) {
+	text := node.Value.(string)
+	switch node.Type {
+	case parser.TypeHexLiteral:
+		return parseHex(text)
+	case parser.TypeAddress:
+		// digits after & are hex, unless written as %binary or 0bbinary with a
+		// lowercase b. &0B10 is the hex address $0B10, the way the disassembler
+		// writes it.
+		if digits, ok := strings.CutPrefix(text, "%"); ok {
+			return parseNumber(text, digits, 2, "binary")
+		}
+		if digits, ok := strings.CutPrefix(text, "0b"); ok && strings.Trim(digits, "01") == "" {
+			return parseNumber(text, digits, 2, "binary")
+		}
+		if strings.Trim(text, "$0123456789abcdefABCDEF") != "" {
+			return 0, fmt.Errorf("&%s is not a hex address, names go in brackets: &[!%s]", text, text)
+		}
+		return parseHex(text)
+	case parser.TypeDecimalLiteral:
+		return parseNumber(text, text, 10, "decimal")
+	case parser.TypeBinaryLiteral:
+		digits, _ := binaryDigits(text)
+		return parseNumber(text, digits, 2, "binary")
+	case parser.TypeCharLiteral:
+		chars, err := unquote(text)
+		if err != nil {
+			return 0, err
+		}
+		if len(chars) != 1 {
+			return 0, fmt.Errorf("character literal %s must be a single character", text)
+		}
+		return int(chars[0]), nil
+	}
+	return 0, fmt.Errorf("%s is not a literal", node.Type)
+}
+
+// parseHex parses a hex number with an optional "$" or "&" prefix
+func parseHex(s string) (int, error) {
+	return parseNumber(s, strings.TrimLeft(s, "$&"), 16, "hex")
+}
+
+// binaryDigits strips the "%", "0b" or "0B" prefix of a binary literal
+func binaryDigits(s string) (string, bool) {
+	for _, prefix := range []string{"%", "0b", "0B"} {
+		if digits, ok := strings.CutPrefix(s, prefix); ok {
+			return digits, true
+		}
+	}
+	return s, false
+}
+
+// parseNumber parses the digits of the literal s, which must fit in 16 bits
+func parseNumber(s, digits string, base int, name string) (int, error) {
+	value, err := strconv.ParseUint(digits, base, 16)
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, fmt.Errorf("%s value %s does not fit in 16 bits", name, s)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s value %q", name, s)
+	}
+	return int(value), nil
+}
+
+// unquote decodes a quoted string or character literal. The escapes are
+// \n \r \t \0 \\ \" \' and \xHH.
+func unquote(text string) ([]byte, error) {
+	var decoded []byte
+	body := text[1 : len(text)-1]
+	for i := 0; i < len(body); i++ {
+		if body[i] != '\\' {
+			decoded = append(decoded, body[i])
+			continue
+		}
+
+		i++
+		switch body[i] {
+		case 'n':
+			decoded = append(decoded, '\n')
+		case 'r':
+			decoded = append(decoded, '\r')
+		case 't':
+			decoded = append(decoded, '\t')
+		case '0':
+			decoded = append(decoded, 0)
+		case '\\', '"', '\'':
+			decoded = append(decoded, body[i])
+		case 'x':
+			if i+3 > len(body) {
+				return nil, fmt.Errorf("\\x in %s needs two hex digits", text)
+			}
+			value, err := strconv.ParseUint(body[i+1:i+3], 16, 8)
+			if err != nil {
+				return nil, fmt.Errorf("\\x in %s needs two hex digits", text)
+			}
+			decoded = append(decoded, byte(value))
+			i += 2
+		default:
+			return nil, fmt.Errorf("unknown escape \\%c in %s", body[i], text)
+		}
+	}
+	return decoded, nil
+}
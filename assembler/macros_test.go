@@ -0,0 +1,101 @@
+package assembler
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestMacroExpansion assembles programs using macros and rept blocks and
+// compares them with the same program written out by hand.
+func TestMacroExpansion(t *testing.T) {
+	tests := []struct {
+		name     string
+		program  string
+		expanded string
+	}{
+		{
+			name: "local labels",
+			program: `macro spin
+loop:
+  jne $00, &[!loop]
+endm
+spin
+spin`,
+			expanded: `first:
+  jne $00, &[!first]
+second:
+  jne $00, &[!second]`,
+		},
+		{
+			name: "default parameter",
+			program: `macro add_to reg, value = 5
+  add value, reg
+endm
+add_to r1
+add_to r2, 7
+add_to r3,`,
+			expanded: `add 5, r1
+add 7, r2
+add 5, r3`,
+		},
+		{
+			name: "rept counter",
+			program: `rept 3, i
+  mov [i * 2], r1
+endr`,
+			expanded: `mov 0, r1
+mov 2, r1
+mov 4, r1`,
+		},
+		{
+			name: "rept with a local label",
+			program: `rept 2
+next:
+  mov &[!next], r1
+endr`,
+			expanded: `first:
+  mov &[!first], r1
+second:
+  mov &[!second], r1`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Assemble(test.program)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := Assemble(test.expanded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Code, want.Code) {
+				t.Errorf("assembled to % X, want % X", got.Code, want.Code)
+			}
+		})
+	}
+}
+
+func TestRecursiveMacro(t *testing.T) {
+	_, err := Assemble(`macro forever
+  forever
+endm
+forever`)
+	var assembleErr *Error
+	if !errors.As(err, &assembleErr) || len(assembleErr.Diagnostics) != 1 {
+		t.Fatalf("error = %v, want one diagnostic", err)
+	}
+	d := assembleErr.Diagnostics[0]
+	if !strings.Contains(d.Message, "nested too deeply") || d.Pos.Line != 2 {
+		t.Errorf("diagnostic = %s, want the nesting reported on line 2", d)
+	}
+	var definedHere bool
+	for _, note := range d.Notes {
+		definedHere = definedHere || strings.Contains(note.Message, "defined here") && note.Pos.Line == 1
+	}
+	if !definedHere {
+		t.Errorf("diagnostic = %s, want a note at the definition on line 1", d)
+	}
+}
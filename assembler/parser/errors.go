@@ -0,0 +1,105 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/participle/v2"
+)
+
+// Position is a place in the source, lines and columns start at 1
+type Position struct {
+	File      string
+	Line      int
+	Column    int
+	Expansion *Expansion // set when the statement came from a macro or rept block
+}
+
+func (p Position) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// Error is a parse error at a position in the source
+type Error struct {
+	Pos     Position
+	Message string
+	Notes   []*Error // related places, such as the definition of a macro
+}
+
+func (e *Error) Error() string {
+	return e.Pos.String() + ": " + e.Message
+}
+
+// ErrorList is every parse error found in a program, in source order
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	messages := make([]string, 0, len(l))
+	for _, e := range l {
+		messages = append(messages, e.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// source converts byte offsets in a file into line and column positions
+type source struct {
+	text       string
+	file       string
+	from       *Expansion // the include that brought the file in
+	lineStarts []int
+}
+
+func newSource(text, file string, from *Expansion) *source {
+	s := &source{text: text, file: file, from: from, lineStarts: []int{0}}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			s.lineStarts = append(s.lineStarts, i+1)
+		}
+	}
+	return s
+}
+
+func (s *source) position(offset int) Position {
+	line := sort.Search(len(s.lineStarts), func(i int) bool { return s.lineStarts[i] > offset }) - 1
+	return Position{File: s.file, Line: line + 1, Column: offset - s.lineStarts[line] + 1, Expansion: s.from}
+}
+
+// statement is the text of the span, its positions are in s
+func (s *source) statement(sp span) statement {
+	return statement{text: sp.text, locate: func(offset int) Position {
+		return s.position(sp.offset + offset)
+	}}
+}
+
+// syntaxError converts an error from parsing the statement
+func syntaxError(s statement, err error) *Error {
+	if pe, ok := err.(participle.Error); ok {
+		return &Error{Pos: s.locate(pe.Position().Offset), Message: pe.Message()}
+	}
+	return &Error{Pos: s.locate(0), Message: err.Error()}
+}
+
+func ordinal(n int) string {
+	switch n {
+	case 1:
+		return "first"
+	case 2:
+		return "second"
+	case 3:
+		return "third"
+	}
+	return fmt.Sprintf("%dth", n)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -1,5 +1,21 @@
 package parser
 
+import "strings"
+
+// Operator priorities, higher binds tighter (same order as in C)
+var priorities = map[NodeType]int{
+	TypeOpMultiply:   5,
+	TypeOpDivide:     5,
+	TypeOpModulo:     5,
+	TypeOpPlus:       4,
+	TypeOpMinus:      4,
+	TypeOpShiftLeft:  3,
+	TypeOpShiftRight: 3,
+	TypeOpAnd:        2,
+	TypeOpXor:        1,
+	TypeOpOr:         0,
+}
+
 // DisambiguateOrderOfOperations transforms the AST to respect operator precedence
 func DisambiguateOrderOfOperations(expr *Node) *Node {
 	// Check if the expression is not a bracketed expression
@@ -12,20 +28,15 @@ func DisambiguateOrderOfOperations(expr *Node) *Node {
 	if !ok {
 		return expr
 	}
+	elements = foldUnaryOperations(splitModulo(elements))
 
 	// If there's only one element, return it directly
 	if len(elements) == 1 {
 		return elements[0]
 	}
 
-	// Define operator priorities
-	priorities := map[NodeType]int{
-		TypeOpMultiply: 2,
-		TypeOpPlus:     1,
-		TypeOpMinus:    0,
-	}
-
-	// Find the highest priority operator
+	// Find the highest priority operator, the leftmost one wins a tie so
+	// operators of the same priority group from the left
 	candidateIndex := -1
 	highestPriority := -1
 
@@ -37,8 +48,10 @@ func DisambiguateOrderOfOperations(expr *Node) *Node {
 		}
 	}
 
-	if candidateIndex == -1 {
-		return expr // No operators found
+	if candidateIndex == -1 || candidateIndex+1 >= len(elements) {
+		// No operators found, or a dangling one. The list is left as it is and
+		// the assembler reports it as a malformed expression.
+		return &Node{Type: expr.Type, Value: elements}
 	}
 
 	// Create binary operation with the highest priority operator
@@ -66,3 +79,60 @@ func DisambiguateOrderOfOperations(expr *Node) *Node {
 	// Recursively process the resulting expression
 	return DisambiguateOrderOfOperations(newExpr)
 }
+
+// splitModulo fixes up "a %10", which the lexer reads as a value followed by the
+// binary literal %10. Where an operator is expected it's a modulo by decimal 10.
+func splitModulo(elements []*Node) []*Node {
+	var split []*Node
+	for _, element := range elements {
+		text, _ := element.Value.(string)
+		if element.Type == TypeBinaryLiteral && strings.HasPrefix(text, "%") &&
+			len(split) > 0 && !isOperator(split[len(split)-1]) {
+			split = append(split,
+				&Node{Type: TypeOpModulo, Value: "%"},
+				&Node{Type: TypeDecimalLiteral, Value: text[1:]})
+			continue
+		}
+		split = append(split, element)
+	}
+	return split
+}
+
+// foldUnaryOperations turns a '-' or '~' that starts the expression or follows
+// another operator into a unary operation on the element after it
+func foldUnaryOperations(elements []*Node) []*Node {
+	var folded []*Node
+	for i := 0; i < len(elements); i++ {
+		element := elements[i]
+		unary := element.Type == TypeOpNot ||
+			(element.Type == TypeOpMinus && (len(folded) == 0 || isOperator(folded[len(folded)-1])))
+		if !unary {
+			folded = append(folded, element)
+			continue
+		}
+
+		// Operators can stack: "- ~x"
+		end := i
+		for end < len(elements) && (elements[end].Type == TypeOpMinus || elements[end].Type == TypeOpNot) {
+			end++
+		}
+		if end == len(elements) {
+			return append(folded, elements[i:]...)
+		}
+		operand := elements[end]
+		for j := end - 1; j >= i; j-- {
+			operand = &Node{
+				Type:  TypeUnaryOperation,
+				Value: map[string]interface{}{"op": elements[j], "value": operand},
+			}
+		}
+		folded = append(folded, operand)
+		i = end
+	}
+	return folded
+}
+
+func isOperator(node *Node) bool {
+	_, binary := priorities[node.Type]
+	return binary || node.Type == TypeOpNot
+}
@@ -0,0 +1,110 @@
+package parser
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+// include "file.asm" parses the file in place of the statement, as if its text
+// was written there. incbin "file.bin" places the bytes of the file.
+//
+// A relative name is looked up in the directory of the including file first,
+// then in Options.IncludePaths.
+
+// runFile parses the text of a file, from is the include that brought it in
+func (e *expander) runFile(text, file string, from *Expansion, depth int) {
+	src := newSource(text, file, from)
+	spans := splitStatements(text)
+	stmts := make([]statement, 0, len(spans))
+	for _, sp := range spans {
+		stmts = append(stmts, src.statement(sp))
+	}
+
+	e.including = append(e.including, file)
+	e.run(stmts, depth)
+	e.including = e.including[:len(e.including)-1]
+}
+
+func (e *expander) include(s statement, tokens []lexer.Token, depth int) {
+	pos := s.locate(tokens[0].Pos.Offset)
+	path, data, ok := e.open(pos, tokens)
+	if !ok {
+		return
+	}
+
+	for i, file := range e.including {
+		if sameFile(file, path) {
+			cycle := append(append([]string{}, e.including[i:]...), path)
+			e.errorf(pos, "include cycle: %s", strings.Join(cycle, " -> "))
+			return
+		}
+	}
+	e.runFile(string(data), path, &Expansion{Include: path, Pos: pos}, depth)
+}
+
+func (e *expander) incbin(s statement, tokens []lexer.Token) {
+	pos := s.locate(tokens[0].Pos.Offset)
+	path, data, ok := e.open(pos, tokens)
+	if !ok {
+		return
+	}
+	e.nodes = append(e.nodes, &Node{
+		Type:  TypeIncbin,
+		Value: map[string]interface{}{"file": path, "data": data},
+		Pos:   pos,
+	})
+}
+
+// open finds and reads the file named by an include or incbin statement
+func (e *expander) open(pos Position, tokens []lexer.Token) (string, []byte, bool) {
+	keyword := tokens[0].Value
+	if len(tokens) != 2 || tokens[1].Type != stringToken {
+		e.errorf(pos, "%s takes a quoted file name", keyword)
+		return "", nil, false
+	}
+	name, err := strconv.Unquote(tokens[1].Value)
+	if err != nil {
+		e.errorf(pos, "%s: invalid file name %s", keyword, tokens[1].Value)
+		return "", nil, false
+	}
+
+	dirs := append([]string{filepath.Dir(pos.File)}, e.options.IncludePaths...)
+	if filepath.IsAbs(name) {
+		dirs = []string{""}
+	}
+	for _, dir := range dirs {
+		path := filepath.Join(dir, name)
+		data, err := e.options.ReadFile(path)
+		if err == nil {
+			return path, data, true
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			e.errorf(pos, "%s: %v", keyword, err)
+			return "", nil, false
+		}
+	}
+	if filepath.IsAbs(name) {
+		e.errorf(pos, "%s: %s not found", keyword, name)
+	} else {
+		e.errorf(pos, "%s: %s not found in %s", keyword, name, strings.Join(dirs, ", "))
+	}
+	return "", nil, false
+}
+
+// sameFile compares two paths after making them absolute
+func sameFile(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
@@ -0,0 +1,119 @@
+package parser
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+// fileSet is an in-memory file system for Options.ReadFile
+type fileSet map[string]string
+
+func (f fileSet) options(includePaths ...string) Options {
+	return Options{
+		Filename:     "/src/main.asm",
+		IncludePaths: includePaths,
+		ReadFile: func(path string) ([]byte, error) {
+			text, ok := f[path]
+			if !ok {
+				return nil, &fs.PathError{Op: "open", Path: path, Err: fs.ErrNotExist}
+			}
+			return []byte(text), nil
+		},
+	}
+}
+
+// parseErrors parses the program and returns its errors
+func parseErrors(t *testing.T, program string, options Options) ErrorList {
+	t.Helper()
+	_, err := ParseProgram(program, options)
+	var errs ErrorList
+	if !errors.As(err, &errs) {
+		t.Fatalf("error = %v, want an ErrorList", err)
+	}
+	return errs
+}
+
+func TestIncludeSearch(t *testing.T) {
+	files := fileSet{
+		"/src/lib.asm":  "near:\n",
+		"/inc/lib.asm":  "far:\n",
+		"/inc/util.asm": "util:\n",
+		"/inc/data.bin": "\x01\x02",
+	}
+	nodes, err := ParseProgram("include \"lib.asm\"\ninclude \"util.asm\"\nincbin \"data.bin\"\n", files.options("/inc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		kind  NodeType
+		value string
+		file  string
+	}{
+		{TypeLabel, "near", "/src/lib.asm"},
+		{TypeLabel, "util", "/inc/util.asm"},
+		{TypeIncbin, "/inc/data.bin", "/src/main.asm"},
+	}
+	if len(nodes) != len(want) {
+		t.Fatalf("got %d nodes, want %d", len(nodes), len(want))
+	}
+	for i, w := range want {
+		n := nodes[i]
+		value := n.Value.(map[string]interface{})
+		got, _ := value["label"].(string)
+		if n.Type == TypeIncbin {
+			got, _ = value["file"].(string)
+		}
+		if n.Type != w.kind || got != w.value || n.Pos.File != w.file {
+			t.Errorf("node %d = %s %q in %s, want %s %q in %s", i, n.Type, got, n.Pos.File, w.kind, w.value, w.file)
+		}
+	}
+}
+
+func TestIncludeNotFound(t *testing.T) {
+	tests := []struct {
+		program string
+		want    string
+	}{
+		{`include "missing.asm"`, "include: missing.asm not found in /src, /inc"},
+		{`incbin "missing.bin"`, "incbin: missing.bin not found in /src, /inc"},
+		{`include "/abs/missing.asm"`, "include: /abs/missing.asm not found"},
+	}
+	for _, test := range tests {
+		errs := parseErrors(t, test.program, fileSet{}.options("/inc"))
+		if len(errs) != 1 || errs[0].Message != test.want {
+			t.Errorf("%s: errors = %v, want %q", test.program, errs, test.want)
+		}
+	}
+}
+
+func TestIncludeCycle(t *testing.T) {
+	files := fileSet{
+		"/src/a.asm": "include \"b.asm\"\n",
+		"/src/b.asm": "hlt\ninclude \"a.asm\"\n",
+	}
+	errs := parseErrors(t, `include "a.asm"`, files.options())
+	want := "include cycle: /src/a.asm -> /src/b.asm -> /src/a.asm"
+	if len(errs) != 1 || errs[0].Message != want {
+		t.Fatalf("errors = %v, want %q", errs, want)
+	}
+	if pos := errs[0].Pos; pos.File != "/src/b.asm" || pos.Line != 2 {
+		t.Errorf("cycle reported at %s, want /src/b.asm:2", pos)
+	}
+}
+
+func TestIncludeErrorPosition(t *testing.T) {
+	files := fileSet{"/src/lib.asm": "hlt\n\n  mov r1\n"}
+	errs := parseErrors(t, "hlt\ninclude \"lib.asm\"\n", files.options())
+	if len(errs) != 1 {
+		t.Fatalf("errors = %v, want one", errs)
+	}
+	pos := errs[0].Pos
+	if pos.File != "/src/lib.asm" || pos.Line != 3 {
+		t.Errorf("error at %s, want line 3 of /src/lib.asm", pos)
+	}
+	if from := pos.Expansion; from == nil || from.Include != "/src/lib.asm" || from.Pos.File != "/src/main.asm" || from.Pos.Line != 2 {
+		t.Errorf("expansion = %+v, want the include on /src/main.asm:2", from)
+	}
+}
@@ -0,0 +1,495 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/participle/v2/lexer"
+	"github.com/martbul/instructions"
+)
+
+// Macros and rept blocks are expanded before statements are parsed. A body is
+// kept as statement text and parameters and local labels are substituted token
+// by token, so every position still points into the source:
+//
+//	macro name param, param = default
+//	  ...
+//	endm
+//
+//	rept count[, counter]
+//	  ...
+//	endr
+//
+// Names defined in a body (labels, data blocks and constants) are local, every
+// expansion renames them.
+
+// maxExpansionDepth stops a macro that invokes itself
+const maxExpansionDepth = 64
+
+// maxRepetitions is the largest rept count, more would not fit in memory anyway
+const maxRepetitions = 0x10000
+
+// Expansion records that a statement came from a macro, a rept block or an
+// included file
+type Expansion struct {
+	Macro   string   // macro name
+	Include string   // included file
+	Pos     Position // the invocation, the rept line or the include
+}
+
+// statement is the text of one statement and where each byte of it came from
+type statement struct {
+	text   string
+	locate func(offset int) Position
+}
+
+// slice is the part of the statement from start to end
+func (s statement) slice(start, end int) statement {
+	return statement{text: s.text[start:end], locate: func(offset int) Position {
+		return s.locate(start + offset)
+	}}
+}
+
+type macro struct {
+	name   string
+	params []macroParam
+	body   []statement
+	locals []string // names defined in the body
+	pos    Position
+}
+
+type macroParam struct {
+	name       string
+	value      string // used when the argument is left out
+	hasDefault bool
+}
+
+// blockKeywords start and end macro definitions and rept blocks
+var blockKeywords = []string{"macro", "endm", "rept", "endr"}
+
+// fileKeywords read other files, see files.go
+var fileKeywords = []string{"include", "incbin"}
+
+// keywords can't be used as macro names
+var keywords = append([]string{"constant", "data8", "data16", "org", "align", "reserve", "ds", "include", "incbin"}, blockKeywords...)
+
+// expander walks the statements of a program, collects macro definitions and
+// parses everything else, expanding invocations and rept blocks on the way
+type expander struct {
+	options    Options
+	macros     map[string]*macro
+	expansions int      // numbers the local names of each expansion
+	including  []string // files being parsed, the last one includes nothing yet
+	nodes      []*Node
+	errs       ErrorList
+}
+
+func (e *expander) errorf(pos Position, format string, args ...interface{}) *Error {
+	err := &Error{Pos: pos, Message: fmt.Sprintf(format, args...)}
+	e.errs = append(e.errs, err)
+	return err
+}
+
+func (e *expander) run(stmts []statement, depth int) {
+	for i := 0; i < len(stmts); i++ {
+		s := stmts[i]
+		if e.plain(s.text) {
+			e.parse(s)
+			continue
+		}
+		tokens := tokenize(s.text)
+
+		switch keyword(tokens) {
+		case "macro":
+			end := blockEnd(stmts, i, "macro", "endm")
+			if end == -1 {
+				e.errorf(s.locate(0), "macro has no endm")
+				return
+			}
+			if depth > 0 {
+				e.errorf(s.locate(0), "macros can only be defined outside of macros and rept blocks")
+			} else {
+				e.define(s, tokens, stmts[i+1:end])
+			}
+			i = end
+
+		case "rept":
+			end := blockEnd(stmts, i, "rept", "endr")
+			if end == -1 {
+				e.errorf(s.locate(0), "rept has no endr")
+				return
+			}
+			e.repeat(s, tokens, stmts[i+1:end], depth)
+			i = end
+
+		case "endm":
+			e.errorf(s.locate(0), "endm without macro")
+		case "endr":
+			e.errorf(s.locate(0), "endr without rept")
+
+		default:
+			e.statement(s, tokens, depth)
+		}
+	}
+}
+
+// plain reports that a statement neither starts a block nor invokes a macro,
+// judging by its first word or the word after its label. Most statements are
+// plain and are parsed without being tokenized first.
+func (e *expander) plain(text string) bool {
+	word, rest := leadingName(text)
+	if rest = strings.TrimLeft(rest, " \t"); strings.HasPrefix(rest, ":") {
+		word, _ = leadingName(strings.TrimLeft(rest[1:], " \t"))
+	} else if contains(blockKeywords, word) {
+		return false
+	}
+	_, exists := e.macros[word]
+	return !exists && !contains(fileKeywords, word)
+}
+
+// leadingName splits an identifier off the start of text
+func leadingName(text string) (string, string) {
+	i := 0
+	for i < len(text) && (text[i] == '_' || 'a' <= text[i] && text[i] <= 'z' || 'A' <= text[i] && text[i] <= 'Z' || i > 0 && '0' <= text[i] && text[i] <= '9') {
+		i++
+	}
+	return text[:i], text[i:]
+}
+
+// statement parses a statement, or expands it if it invokes a macro or
+// includes a file
+func (e *expander) statement(s statement, tokens []lexer.Token, depth int) {
+	name := 0
+	if isLabel(tokens) {
+		name = 2
+	}
+	if name >= len(tokens) || tokens[name].Type != identToken {
+		e.parse(s)
+		return
+	}
+	word := tokens[name].Value
+	m, isMacro := e.macros[word]
+	if !isMacro && !contains(fileKeywords, word) {
+		e.parse(s)
+		return
+	}
+
+	if name > 0 {
+		e.parse(s.slice(0, tokens[1].Pos.Offset+1))
+	}
+	switch {
+	case isMacro:
+		at := tokens[name].Pos.Offset
+		e.invoke(m, s.locate(at), s.text[at+len(m.name):], depth)
+	case word == "include":
+		e.include(s, tokens[name:], depth)
+	default:
+		e.incbin(s, tokens[name:])
+	}
+}
+
+func (e *expander) parse(s statement) {
+	nodes, err := parseStatement(s)
+	if err != nil {
+		e.errs = append(e.errs, err)
+		return
+	}
+	e.nodes = append(e.nodes, nodes...)
+}
+
+// define records a macro. The header is "macro name param, param = default".
+func (e *expander) define(s statement, tokens []lexer.Token, body []statement) {
+	pos := s.locate(0)
+	if len(tokens) < 2 || tokens[1].Type != identToken {
+		e.errorf(pos, "macro needs a name")
+		return
+	}
+	name := tokens[1].Value
+	if other, exists := e.macros[name]; exists {
+		err := e.errorf(s.locate(tokens[1].Pos.Offset), "macro %q is already defined", name)
+		err.Notes = append(err.Notes, &Error{Pos: other.pos, Message: "the other definition is here"})
+		return
+	}
+	if len(instructions.GetInstructionsByMnemonic(strings.ToLower(name))) > 0 || contains(keywords, name) {
+		e.errorf(s.locate(tokens[1].Pos.Offset), "%q can't be used as a macro name", name)
+		return
+	}
+
+	m := &macro{name: name, body: body, pos: pos}
+	for _, param := range splitArguments(s.text[tokens[1].Pos.Offset+len(name):]) {
+		paramName, value, hasDefault := strings.Cut(param, "=")
+		paramName = strings.TrimSpace(paramName)
+		if !isName(paramName) {
+			e.errorf(pos, "macro %s: %q is not a valid parameter name", name, paramName)
+			return
+		}
+		for _, other := range m.params {
+			if other.name == paramName {
+				e.errorf(pos, "macro %s: parameter %q is listed twice", name, paramName)
+				return
+			}
+		}
+		m.params = append(m.params, macroParam{name: paramName, value: strings.TrimSpace(value), hasDefault: hasDefault})
+	}
+	m.locals = localNames(body)
+	e.macros[name] = m
+}
+
+// invoke expands the macro called at pos with the argument text after its name
+func (e *expander) invoke(m *macro, pos Position, argText string, depth int) {
+	defined := &Error{Pos: m.pos, Message: fmt.Sprintf("macro %s is defined here", m.name)}
+	if depth >= maxExpansionDepth {
+		err := e.errorf(pos, "macro %s is nested too deeply, does it invoke itself?", m.name)
+		err.Notes = append(err.Notes, defined)
+		return
+	}
+
+	args := splitArguments(argText)
+	if len(args) > len(m.params) {
+		err := e.errorf(pos, "macro %s takes %d arguments but is given %d", m.name, len(m.params), len(args))
+		err.Notes = append(err.Notes, defined)
+		return
+	}
+
+	replace := make(map[string]string)
+	for i, param := range m.params {
+		switch {
+		case i < len(args) && args[i] != "":
+			replace[param.name] = args[i]
+		case param.hasDefault:
+			replace[param.name] = param.value
+		default:
+			err := e.errorf(pos, "macro %s is missing the argument %s", m.name, param.name)
+			err.Notes = append(err.Notes, defined)
+			return
+		}
+	}
+	e.expand(m.body, m.locals, replace, &Expansion{Macro: m.name, Pos: pos}, depth)
+}
+
+// repeat expands a rept block. The header is "rept count[, counter]", the
+// counter is replaced by the number of the repetition starting from 0.
+func (e *expander) repeat(s statement, tokens []lexer.Token, body []statement, depth int) {
+	pos := s.locate(0)
+	args := splitArguments(s.text[tokens[0].Pos.Offset+len(tokens[0].Value):])
+	if len(args) == 0 || len(args) > 2 {
+		e.errorf(pos, "rept takes a count and an optional counter name")
+		return
+	}
+	count, err := parseCount(args[0])
+	if err != nil || count > maxRepetitions {
+		e.errorf(pos, "rept count %q must be a number up to %d", args[0], maxRepetitions)
+		return
+	}
+	counter := ""
+	if len(args) == 2 {
+		if counter = args[1]; !isName(counter) {
+			e.errorf(pos, "rept: %q is not a valid counter name", counter)
+			return
+		}
+	}
+	if depth >= maxExpansionDepth {
+		e.errorf(pos, "rept is nested too deeply")
+		return
+	}
+
+	locals := localNames(body)
+	for n := 0; n < count; n++ {
+		replace := make(map[string]string)
+		if counter != "" {
+			replace[counter] = strconv.Itoa(n)
+		}
+		e.expand(body, locals, replace, &Expansion{Pos: pos}, depth)
+	}
+}
+
+// expand substitutes the replacements and fresh names for the local names
+// into the body and runs the result
+func (e *expander) expand(body []statement, locals []string, replace map[string]string, expansion *Expansion, depth int) {
+	e.expansions++
+	for _, label := range locals {
+		replace[label] = fmt.Sprintf("%s__%d", label, e.expansions)
+	}
+	expanded := make([]statement, 0, len(body))
+	for _, s := range body {
+		expanded = append(expanded, substitute(s, replace, expansion))
+	}
+	e.run(expanded, depth+1)
+}
+
+// segment maps part of a substituted statement back to the original
+type segment struct {
+	at       int  // offset in the substituted text
+	original int  // offset in the original text
+	replaced bool // a replaced token maps entirely to where the token was
+}
+
+// substitute replaces the identifiers in replace and marks every position as
+// coming from the expansion
+func substitute(s statement, replace map[string]string, expansion *Expansion) statement {
+	var text strings.Builder
+	var segments []segment
+	tokens, err := lexTokens(s.text)
+	if err != nil {
+		// the parser reports the bad input
+		tokens = []lexer.Token{{Value: s.text}}
+	}
+	for _, token := range tokens {
+		value, replaced := replace[token.Value]
+		replaced = replaced && token.Type == identToken
+		if !replaced {
+			value = token.Value
+		}
+		segments = append(segments, segment{at: text.Len(), original: token.Pos.Offset, replaced: replaced})
+		text.WriteString(value)
+	}
+
+	return statement{text: text.String(), locate: func(offset int) Position {
+		i := sort.Search(len(segments), func(i int) bool { return segments[i].at > offset }) - 1
+		original := 0
+		if i >= 0 {
+			original = segments[i].original
+			if !segments[i].replaced {
+				original += offset - segments[i].at
+			}
+		}
+		pos := s.locate(original)
+		pos.Expansion = expansion
+		return pos
+	}}
+}
+
+var (
+	identToken      = lexerDef.Symbols()["Ident"]
+	stringToken     = lexerDef.Symbols()["String"]
+	whitespaceToken = lexerDef.Symbols()["Whitespace"]
+	commentToken    = lexerDef.Symbols()["Comment"]
+)
+
+// lexTokens splits text into all of its tokens, whitespace included
+func lexTokens(text string) ([]lexer.Token, error) {
+	lex, err := lexerDef.LexString("", text)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := lexer.ConsumeAll(lex)
+	if err != nil {
+		return nil, err
+	}
+	return tokens[:len(tokens)-1], nil // drop EOF
+}
+
+// tokenize returns the tokens of text without whitespace and comments
+func tokenize(text string) []lexer.Token {
+	all, err := lexTokens(text)
+	if err != nil {
+		return nil
+	}
+	tokens := all[:0]
+	for _, token := range all {
+		if token.Type != whitespaceToken && token.Type != commentToken {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// keyword is the first word of a statement unless it's a label
+func keyword(tokens []lexer.Token) string {
+	if len(tokens) == 0 || tokens[0].Type != identToken || isLabel(tokens) {
+		return ""
+	}
+	return tokens[0].Value
+}
+
+func isLabel(tokens []lexer.Token) bool {
+	return len(tokens) >= 2 && tokens[0].Type == identToken && tokens[1].Value == ":"
+}
+
+func isName(text string) bool {
+	tokens := tokenize(text)
+	return len(tokens) == 1 && tokens[0].Type == identToken && tokens[0].Value == text
+}
+
+// blockEnd finds the statement that closes the block opened at start
+func blockEnd(stmts []statement, start int, open, close string) int {
+	depth := 0
+	for i := start; i < len(stmts); i++ {
+		switch keyword(tokenize(stmts[i].text)) {
+		case open:
+			depth++
+		case close:
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// localNames lists the labels, data blocks and constants defined in a body
+func localNames(body []statement) []string {
+	var names []string
+	add := func(name string) {
+		if !contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	for _, s := range body {
+		tokens := tokenize(s.text)
+		if isLabel(tokens) {
+			add(tokens[0].Value)
+			tokens = tokens[2:]
+		}
+		if len(tokens) > 0 && tokens[0].Value == "+" {
+			tokens = tokens[1:]
+		}
+		if len(tokens) >= 2 && contains([]string{"constant", "data8", "data16"}, tokens[0].Value) && tokens[1].Type == identToken {
+			add(tokens[1].Value)
+		}
+	}
+	return names
+}
+
+// splitArguments splits text at the commas that are not inside brackets,
+// braces or quotes
+func splitArguments(text string) []string {
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	var args []string
+	depth, start := 0, 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '"', '\'':
+			i = skipQuoted(text, i)
+		case '[', '(', '{':
+			depth++
+		case ']', ')', '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, strings.TrimSpace(text[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(args, strings.TrimSpace(text[start:]))
+}
+
+// parseCount parses a rept count written as a decimal, $hex or %binary number
+func parseCount(text string) (int, error) {
+	base := 10
+	switch {
+	case strings.HasPrefix(text, "$"):
+		base, text = 16, text[1:]
+	case strings.HasPrefix(text, "%"):
+		base, text = 2, text[1:]
+	case strings.HasPrefix(text, "0b"), strings.HasPrefix(text, "0B"):
+		base, text = 2, text[2:]
+	}
+	count, err := strconv.ParseUint(text, base, 32)
+	return int(count), err
+}
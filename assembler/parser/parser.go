@@ -1,358 +1,151 @@
 package parser
 
 import (
-	"fmt"
+	"os"
 	"strings"
-)
-
-var constantsMap = make(map[string]string)
-
-// ParseProgram parses a complete program consisting of instructions and labels
-//func ParseProgram(input string) ([]*Node, error) {
-//	var nodes []*Node
-//	constantsMap = make(map[string]string) // Reset constants map
-//	// Trim leading whitespace
-//	remaining := strings.TrimSpace(input)
-
-//	for len(remaining) > 0 {
-//		if isConstantLine(remaining) {
-//			node, rest, err := parseConstant(remaining)
-//			if err != nil {
-//				return nil, fmt.Errorf("error parsing constant: %v", err)
-//			}
-//			// Store the constant in our map
-//			constantValue := node.Value.(map[string]interface{})
-//			constantsMap[constantValue["name"].(string)] = constantValue["value"].(map[string]interface{})["value"].(string)
-//			nodes = append(nodes, node)
-//			remaining = strings.TrimSpace(rest)
-//			continue
-//		}
-// Try to parse either an instruction or label
-//		node, rest, err := parseInstructionOrLabel(remaining)
-//		if err != nil {
-
-//			return nil, fmt.Errorf("parse error at '%s': %v", remaining, err)
-//		}
-
-//		nodes = append(nodes, node)
-
-// Update remaining input
-///		remaining = strings.TrimSpace(rest)
-//	}
-
-//		return nodes, nil
-//	}
-//
-// ParseProgram parses a complete program consisting of instructions and labels
-func ParseProgram(input string) ([]*Node, error) {
-	var nodes []*Node
-	constantsMap = make(map[string]string) // Reset constants map
-	remaining := strings.TrimSpace(input)
-
-	for len(remaining) > 0 {
-		if isConstantLine(remaining) {
-			node, rest, err := parseConstant(remaining)
-			if err != nil {
-				return nil, fmt.Errorf("error parsing constant: %v", err)
-			}
-
-			// Safely extract constant value
-			constantValue, ok := node.Value.(map[string]interface{})
-			if !ok {
-				return nil, fmt.Errorf("invalid constant format")
-			}
 
-			name, ok := constantValue["name"].(string)
-			if !ok {
-				return nil, fmt.Errorf("invalid constant name")
-			}
-
-			value, ok := constantValue["value"]
-			if !ok {
-				return nil, fmt.Errorf("missing constant value")
-			}
-
-			// Handle both string and map value types
-			var valueStr string
-			switch v := value.(type) {
-			case string:
-				valueStr = v
-			case map[string]interface{}:
-				if val, exists := v["value"]; exists {
-					if strVal, ok := val.(string); ok {
-						valueStr = strVal
-					} else {
-						return nil, fmt.Errorf("invalid constant value type")
-					}
-				} else {
-					return nil, fmt.Errorf("missing nested constant value")
-				}
-			default:
-				return nil, fmt.Errorf("unexpected constant value type")
-			}
-
-			constantsMap[name] = valueStr
-			nodes = append(nodes, node)
-			remaining = strings.TrimSpace(rest)
-			continue
-		}
-
-		node, rest, err := parseInstructionOrLabel(remaining)
-		if err != nil {
-			return nil, fmt.Errorf("parse error at '%s': %v", remaining, err)
-		}
+	"github.com/alecthomas/participle/v2"
+	"github.com/alecthomas/participle/v2/lexer"
+)
 
-		nodes = append(nodes, node)
-		remaining = strings.TrimSpace(rest)
-	}
+// statementParser is built once and used for every statement of every program
+var statementParser = participle.MustBuild[Statement](
+	participle.Lexer(lexerDef),
+	participle.Elide("Whitespace", "Comment"),
+	participle.UseLookahead(2),
+)
 
-	return nodes, nil
+// Options controls where ParseProgram finds included files
+type Options struct {
+	Filename     string                            // name of the program in positions, its includes are looked up next to it
+	IncludePaths []string                          // searched after the directory of the including file
+	ReadFile     func(path string) ([]byte, error) // os.ReadFile when nil
 }
-func resolveConstantReferences(input string) (string, error) {
-	var result strings.Builder
-	i := 0
 
-	for i < len(input) {
-		if i+1 < len(input) && input[i] == '[' && input[i+1] == '!' {
-			// Found constant reference
-			end := strings.IndexByte(input[i:], ']')
-			if end == -1 {
-				return "", fmt.Errorf("unclosed constant reference")
-			}
-			end += i
-
-			constantName := input[i+2 : end]
-			constantValue, exists := constantsMap[constantName]
-			if !exists {
-				return "", fmt.Errorf("undefined constant: %s", constantName)
-			}
-
-			result.WriteString(constantValue)
-			i = end + 1
-		} else {
-			result.WriteByte(input[i])
-			i++
-		}
+// ParseProgram parses a complete program consisting of instructions and labels.
+// A statement that fails to parse is reported and skipped, so the returned
+// ErrorList holds every error in the program.
+func ParseProgram(input string, options ...Options) ([]*Node, error) {
+	var opts Options
+	if len(options) > 0 {
+		opts = options[0]
 	}
-
-	return result.String(), nil
-}
-
-// Add to parseInstructionOrLabel in parser.go
-func parseInstructionOrLabel(input string) (*Node, string, error) {
-
-	if isDataDeclaration(input) {
-		return parseDataDeclaration(input)
+	if opts.ReadFile == nil {
+		opts.ReadFile = os.ReadFile
 	}
 
-	if isLabelLine(input) {
-		return parseLabel(input)
-	}
-	if isConstantLine(input) {
-		return parseConstant(input)
-	}
-	// If not a label or data declaration, try parsing as an instruction
-	instruction, restAfterInstruction, err := parseInstruction(input)
-	if err != nil {
-		fmt.Println("hhh")
+	e := &expander{macros: make(map[string]*macro), options: opts}
+	e.runFile(input, opts.Filename, nil, 0)
 
-		return nil, input, fmt.Errorf("failed to parse as instruction, label, or data declaration: %v", err)
+	if len(e.errs) > 0 {
+		return e.nodes, e.errs
 	}
-
-	return instruction, restAfterInstruction, nil
-}
-
-func isConstantLine(input string) bool {
-	trimmed := strings.TrimSpace(input)
-	return strings.HasPrefix(trimmed, "constant") || strings.HasPrefix(trimmed, "+constant")
+	return e.nodes, nil
 }
 
-func parseConstant(input string) (*Node, string, error) {
-	endIndex := strings.IndexAny(input, "\n;") //gets only the single line with the constant
-	var constantText string
-	var rest string
-
-	if endIndex == -1 {
-		// No newline or semicolon, assume constant takes the whole input
-		constantText = strings.TrimSpace(input)
-		rest = ""
-	} else {
-		constantText = strings.TrimSpace(input[:endIndex])
-		rest = input[endIndex+1:]
-	}
-
-	node, err := ParseConstant(constantText)
+// parseStatement parses one statement. A label followed by an instruction
+// gives two nodes.
+func parseStatement(s statement) ([]*Node, *Error) {
+	stmt, err := statementParser.ParseString("", s.text)
 	if err != nil {
-		return nil, input, err
-
+		return nil, syntaxError(s, err)
 	}
 
-	return node, rest, nil
-}
-
-// isDataDeclaration checks if the line starts with a data declaration
-func isDataDeclaration(input string) bool {
-	trimmed := strings.TrimSpace(input)
-	return strings.HasPrefix(trimmed, "data8") ||
-		strings.HasPrefix(trimmed, "data16") ||
-		strings.HasPrefix(trimmed, "+data8") ||
-		strings.HasPrefix(trimmed, "+data16")
-}
-
-// parseDataDeclaration attempts to parse a data declaration
-func parseDataDeclaration(input string) (*Node, string, error) {
-	// Find where the declaration ends (closing brace followed by whitespace or EOF)
-	closeBraceIndex := strings.Index(input, "}")
-	if closeBraceIndex == -1 {
-		return nil, input, fmt.Errorf("missing closing brace in data declaration")
+	at := func(pos lexer.Position) Position {
+		return s.locate(pos.Offset)
 	}
 
-	// Include the closing brace in the declaration
-	declarationText := strings.TrimSpace(input[:closeBraceIndex+1])
-	rest := input[closeBraceIndex+1:]
-
-	// Determine if it's data8 or data16
-	isData8 := strings.Contains(declarationText, "data8")
-
-	var node *Node
-	var err error
-
-	if isData8 {
-		node, err = ParseData8(declarationText)
-	} else {
-		node, err = ParseData16(declarationText)
-	}
-
-	if err != nil {
-		return nil, input, err
+	var nodes []*Node
+	if stmt.Label != nil {
+		nodes = append(nodes, stmt.Label.AsNode(at))
+	}
+	switch {
+	case stmt.Constant != nil:
+		nodes = append(nodes, stmt.Constant.AsNode(at))
+	case stmt.Data != nil:
+		nodes = append(nodes, stmt.Data.AsNode(at))
+	case stmt.Directive != nil:
+		nodes = append(nodes, stmt.Directive.AsNode(at))
+	case stmt.Instruction != nil:
+		node, err := instructionNode(stmt.Instruction, at, s.locate(len(s.text)))
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
 	}
-
-	return node, rest, nil
+	return nodes, nil
 }
 
-// isLabelLine checks if the line starts with a label
-func isLabelLine(input string) bool {
-	// In most assembly languages, labels end with a colon
-	// And are not indented (for simplicity we'll ignore whitespace)
-	trimmed := strings.TrimSpace(input)
-
-	// Label can't start with a digit
-	if len(trimmed) == 0 || (trimmed[0] >= '0' && trimmed[0] <= '9') {
-		return false
-	}
-
-	// Look for a colon that's not inside a string or comment
-	colonIndex := strings.Index(trimmed, ":")
-	if colonIndex == -1 {
-		return false
-	}
-
-	// Check if what's before the colon is a valid identifier
-	labelName := trimmed[:colonIndex]
-	return isValidIdentifier(labelName)
+// span is a statement's text and where it starts in the program
+type span struct {
+	offset int
+	text   string
 }
 
-// isValidIdentifier checks if a string is a valid label identifier
-func isValidIdentifier(s string) bool {
-	if len(s) == 0 {
-		return false
-	}
-
-	// Must start with a letter or underscore
-	if !((s[0] >= 'a' && s[0] <= 'z') ||
-		(s[0] >= 'A' && s[0] <= 'Z') ||
-		s[0] == '_') {
-		return false
-	}
+// splitStatements splits a program into one statement per line. A comment runs
+// from ';' or '//' to the end of the line. The braces of a data declaration may
+// span several lines, strings and characters are skipped.
+func splitStatements(input string) []span {
+	var spans []span
+	start := 0
+	inBraces := false
+	for i := 0; i <= len(input); i++ {
+		end := i
+		if i < len(input) {
+			switch input[i] {
+			case '"', '\'':
+				i = skipQuoted(input, i)
+				continue
+			case '{':
+				inBraces = strings.IndexByte(input[i:], '}') != -1
+				continue
+			case '}':
+				inBraces = false
+				continue
+			case ';', '/':
+				if input[i] == '/' && !strings.HasPrefix(input[i:], "//") {
+					continue
+				}
+				i += strings.IndexByte(input[i:]+"\n", '\n')
+				if inBraces {
+					// the lexer skips comments inside the braces
+					i--
+					continue
+				}
+			case '\n':
+				if inBraces {
+					continue
+				}
+			default:
+				continue
+			}
+		}
 
-	// Rest can be alphanumeric or underscore
-	for i := 1; i < len(s); i++ {
-		if !((s[i] >= 'a' && s[i] <= 'z') ||
-			(s[i] >= 'A' && s[i] <= 'Z') ||
-			(s[i] >= '0' && s[i] <= '9') ||
-			s[i] == '_') {
-			return false
+		text := input[start:end]
+		trimmed := strings.TrimLeft(text, whitespace)
+		offset := start + len(text) - len(trimmed)
+		if trimmed = strings.TrimRight(trimmed, whitespace); trimmed != "" {
+			spans = append(spans, span{offset: offset, text: trimmed})
 		}
+		start = i + 1
 	}
-
-	return true
+	return spans
 }
 
-// parseLabel attempts to parse a label
-func parseLabel(input string) (*Node, string, error) {
-	// Find the end of the label (colon)
-	colonIndex := strings.Index(input, ":")
-	if colonIndex == -1 {
-		return nil, input, fmt.Errorf("not a label")
-	}
-
-	labelName := strings.TrimSpace(input[:colonIndex])
-	if len(labelName) == 0 {
-		return nil, input, fmt.Errorf("empty label name")
-	}
-
-	// Create a label node
-	labelNode := &Node{
-		Type: TypeLabel, // Use constant instead of string literal
-		Value: map[string]interface{}{
-			"label": labelName,
-		},
+// skipQuoted returns the index of the quote that closes the one at start. An
+// unterminated quote ends with its line, the lexer reports it.
+func skipQuoted(input string, start int) int {
+	for i := start + 1; i < len(input); i++ {
+		switch input[i] {
+		case '\\':
+			i++
+		case input[start]:
+			return i
+		case '\n':
+			return i - 1
+		}
 	}
-
-	// Return the rest of the input after the colon
-	rest := input[colonIndex+1:]
-	return labelNode, rest, nil
+	return len(input) - 1
 }
 
-// parseInstruction attempts to parse an instruction and returns the consumed input
-//func parseInstruction(input string) (*Node, string, error) {
-// Find where the instruction ends (newline or semicolon)
-//	endIndex := strings.IndexAny(input, "\n;")
-//	var instructionText string
-//	var rest string
-//
-//	if endIndex == -1 {
-//		// No newline or semicolon, assume instruction takes the whole input
-//		instructionText = strings.TrimSpace(input)
-//		rest = ""
-//	} else {
-//		instructionText = strings.TrimSpace(input[:endIndex])
-//		rest = input[endIndex+1:]
-//	}
-
-//	fmt.Println(instructionText)
-// Use the existing ParseInstruction function from instructions.go
-//	node, err := ParseInstruction(instructionText)
-//	if err != nil {
-//		return nil, input, err
-//	}
-
-//	return node, rest, nil
-////}
-
-func parseInstruction(input string) (*Node, string, error) {
-	endIndex := strings.IndexAny(input, "\n;")
-	var instructionText string
-	var rest string
-
-	if endIndex == -1 {
-		instructionText = strings.TrimSpace(input)
-		rest = ""
-	} else {
-		instructionText = strings.TrimSpace(input[:endIndex])
-		rest = input[endIndex+1:]
-	}
-
-	// Resolve constant references before parsing
-	resolvedInstruction, err := resolveConstantReferences(instructionText)
-	if err != nil {
-		return nil, input, fmt.Errorf("error resolving constants: %v", err)
-	}
-
-	node, err := ParseInstruction(resolvedInstruction)
-	if err != nil {
-		return nil, input, err
-	}
-
-	return node, rest, nil
-}
+const whitespace = " \t\r\n"
@@ -1,5 +1,7 @@
 package parser
 
+import "github.com/alecthomas/participle/v2/lexer"
+
 // === TypeSystem === //
 // NodeType represents the type of AST node
 type NodeType string
@@ -9,463 +11,297 @@ const (
 	TypeLabel             NodeType = "LABEL"
 	TypeRegisterPointer   NodeType = "REGISTER_POINTER"
 	TypeHexLiteral        NodeType = "HEX_LITERAL"
+	TypeDecimalLiteral    NodeType = "DECIMAL_LITERAL"
+	TypeBinaryLiteral     NodeType = "BINARY_LITERAL"
+	TypeCharLiteral       NodeType = "CHAR_LITERAL"
+	TypeStringLiteral     NodeType = "STRING_LITERAL"
+	TypeAddress           NodeType = "ADDRESS"
+	TypeMemoryReference   NodeType = "MEMORY_REFERENCE"
 	TypeVariable          NodeType = "VARIABLE"
 	TypeOpPlus            NodeType = "OP_PLUS"
 	TypeOpMinus           NodeType = "OP_MINUS"
 	TypeOpMultiply        NodeType = "OP_MULTIPLY"
+	TypeOpDivide          NodeType = "OP_DIVIDE"
+	TypeOpModulo          NodeType = "OP_MODULO"
+	TypeOpShiftLeft       NodeType = "OP_SHIFT_LEFT"
+	TypeOpShiftRight      NodeType = "OP_SHIFT_RIGHT"
+	TypeOpAnd             NodeType = "OP_AND"
+	TypeOpOr              NodeType = "OP_OR"
+	TypeOpXor             NodeType = "OP_XOR"
+	TypeOpNot             NodeType = "OP_NOT"
 	TypeSquareBracketExpr NodeType = "SQUARE_BRACKET_EXPRESSION"
 	TypeBracketedExpr     NodeType = "BRACKETED_EXPRESSION"
 	TypeBinaryOperation   NodeType = "BINARY_OPERATION"
+	TypeUnaryOperation    NodeType = "UNARY_OPERATION"
 	TypeInstruction       NodeType = "INSTRUCTION"
-	TypeData              NodeType = "DATA"
+	TypeData              NodeType = "DATA_DECLARATION"
 	TypeConstant          NodeType = "CONSTANT"
+	TypeDirective         NodeType = "DIRECTIVE"
+	TypeIncbin            NodeType = "INCBIN"
 )
 
 // Node represents a generic AST node with type and value
 type Node struct {
 	Type  NodeType    `json:"type"`
 	Value interface{} `json:"value"`
+	Pos   Position    `json:"-"` // set on statements and instruction operands
 }
 
-// === AST Nodes ===
-type Register struct {
-	Value string `parser:"@Register" json:"value"`
-}
+// === Grammar === //
+// The whole language is this one grammar, built once in parser.go. Instructions are
+// parsed as a mnemonic and a list of operands; which opcode that is gets decided
+// afterwards from the instruction metadata, see instructions.go.
 
-// AsNode converts Register to Node
-func (r *Register) AsNode() *Node {
-	return &Node{
-		Type:  TypeRegister,
-		Value: r.Value,
-	}
+// Statement is an optional label followed by a constant, a data declaration, a
+// directive or an instruction
+type Statement struct {
+	Label       *LabelDecl       `parser:"@@?"`
+	Constant    *ConstantDecl    `parser:"( @@"`
+	Data        *DataDecl        `parser:"| @@"`
+	Directive   *DirectiveStmt   `parser:"| @@"`
+	Instruction *InstructionStmt `parser:"| @@ )?"`
 }
 
-//type HexLiteral struct {
-//	Value string `parser:"'$' @HexDigit" json:"value"`
-//}
-
-//type HexLiteral struct {
-//	Value string `parser:"@HexDigit"`
-//}
-
-//type HexLiteral struct {
-//	Value string `parser:"@Dollar @HexDigit"`
-//}
-
-//	type HexLiteral struct {
-//		Dollar string `parser:"@Dollar"`   // Matches the $ sign explicitly
-//		Value  string `parser:"@HexDigit"` // Matches the hex digits
-//	}
-type HexLiteral struct {
-	Value string `parser:"@HexDigit" json:"value"`
+type LabelDecl struct {
+	Pos  lexer.Position
+	Name string `parser:"@Ident ':'"`
 }
 
-//type HexLiteral struct {
-//	Dollar string `parser:"@Dollar"`
-//	Value  string `parser:"@HexDigit"`
-//}
-
-// AsNode converts HexLiteral to Node
-func (h *HexLiteral) AsNode() *Node {
-	return &Node{
-		Type:  TypeHexLiteral,
-		Value: h.Value,
-	}
+type ConstantDecl struct {
+	Pos      lexer.Position
+	IsExport bool   `parser:"@'+'?"`
+	Name     string `parser:"'constant' @Ident '='"`
+	Value    *Expr  `parser:"@@"`
 }
 
-type Variable struct {
-	Name string `parser:"'!' @Ident" json:"name"`
+type DataDecl struct {
+	Pos      lexer.Position
+	IsExport bool         `parser:"@'+'?"`
+	DataType string       `parser:"@('data8' | 'data16')"`
+	Name     string       `parser:"@Ident '=' '{'"`
+	Values   []*DataValue `parser:"@@ (',' @@)* '}'"`
 }
 
-// AsNode converts Variable to Node
-func (v *Variable) AsNode() *Node {
-	return &Node{
-		Type:  TypeVariable,
-		Value: v.Name,
-	}
-}
-
-// Address represents a direct memory address
-type Address struct {
-	Value string `parser:"'&' @HexDigit" json:"value"`
-}
-
-// AsNode converts Address to Node
-func (a *Address) AsNode() *Node {
-	return &Node{
-		Type:  "ADDRESS",
-		Value: a.Value,
-	}
+// DataValue is a string, which gives one byte per character, or an expression
+type DataValue struct {
+	Pos    lexer.Position
+	String *string `parser:"  @String"`
+	Value  *Expr   `parser:"| @@"`
 }
 
-// RegisterPointer represents a register pointer (e.g., &r1)
-type RegisterPointer struct {
-	Register *Register `parser:"'&' @@" json:"register"`
-}
-
-// AsNode converts RegisterPointer to Node
-func (rp *RegisterPointer) AsNode() *Node {
-	return &Node{
-		Type:  TypeRegisterPointer,
-		Value: rp.Register.Value,
-	}
-}
-
-// MemoryReference represents either a direct address or a square bracket expression with an ampersand
-type MemoryReference struct {
-	DirectAddress *Address           `parser:"  @@"`
-	Expression    *SquareBracketExpr `parser:"| '&' @@"`
-}
-
-// AsNode converts MemoryReference to Node
-func (m *MemoryReference) AsNode() *Node {
-	if m.DirectAddress != nil {
-		return m.DirectAddress.AsNode()
-	}
-
-	// For expressions, create a memory reference node that wraps the square bracket expression
-	exprNode := m.Expression.AsNode()
-	return &Node{
-		Type:  "MEMORY_REFERENCE",
-		Value: exprNode,
-	}
-}
-
-//WARN: Personal fuckup
-
-// MemoryReference represents either a direct address or a square bracket expression with an ampersand
-type LiteralReference struct {
-	DirectLiteral *HexLiteral        `parser:"  @@"`
-	Expression    *SquareBracketExpr `parser:"| '&' @@"`
-}
-
-// AsNode converts MemoryReference to Node
-func (m *LiteralReference) AsNode() *Node {
-	if m.DirectLiteral != nil {
-		return m.DirectLiteral.AsNode()
-	}
-
-	// For expressions, create a memory reference node that wraps the square bracket expression
-	exprNode := m.Expression.AsNode()
-	return &Node{
-		Type:  "LITERAL_REFERENCE",
-		Value: exprNode,
-	}
+// DirectiveStmt controls where the following code is placed
+type DirectiveStmt struct {
+	Pos       lexer.Position
+	Directive string      `parser:"@('org' | 'align' | 'reserve' | 'ds')"`
+	Arguments []*Argument `parser:"@@ (',' @@)*"`
 }
 
-type Operator struct {
-	Symbol string `parser:"@Operator" json:"symbol"`
+// Argument is a directive argument
+type Argument struct {
+	Pos   lexer.Position
+	Value *Expr `parser:"@@"`
 }
 
-// AsNode converts Operator to Node
-func (o *Operator) AsNode() *Node {
-	switch o.Symbol {
-	case "+":
-		return &Node{Type: TypeOpPlus, Value: "+"}
-	case "-":
-		return &Node{Type: TypeOpMinus, Value: "-"}
-	case "*":
-		return &Node{Type: TypeOpMultiply, Value: "*"}
-	default:
-		return &Node{Type: "OP_UNKNOWN", Value: o.Symbol}
-	}
+type InstructionStmt struct {
+	Pos      lexer.Position
+	Mnemonic string     `parser:"@Ident"`
+	Operands []*Operand `parser:"( @@ ( ',' @@ )* )?"`
 }
 
-// ExprElement represents either an expression or an operator
-type ExprElement struct {
-	Expr     *Expr     `parser:"  @@"`
-	Operator *Operator `parser:"| @@"`
+// Operand is one instruction operand, its kind decides which opcode is used
+type Operand struct {
+	Pos      lexer.Position
+	Pointer  *string  `parser:"  '&' @Register"`
+	Address  *Address `parser:"| '&' @@"`
+	Register *string  `parser:"| @Register"`
+	Literal  *Expr    `parser:"| @@"`
 }
 
-// AsNode converts ExprElement to Node
-func (e *ExprElement) AsNode() *Node {
-	if e.Expr != nil {
-		return e.Expr.AsNode()
-	}
-	return e.Operator.AsNode()
+// Address is a memory address, the digits after & are hex even without a $
+// unless they are written as %binary. Digits starting with a letter, like
+// &FFFE, lex as an Ident and are checked when the address is assembled.
+type Address struct {
+	Hex        string             `parser:"  @(HexDigit | Number | Binary | Ident)"`
+	Expression *SquareBracketExpr `parser:"| @@"`
 }
 
 type Expr struct {
-	Hex     *HexLiteral        `parser:"  @@"`
-	Var     *Variable          `parser:"| @@"`
+	Hex     *string            `parser:"  @HexDigit"`
+	Decimal *string            `parser:"| @Number"`
+	Binary  *string            `parser:"| @Binary"`
+	Char    *string            `parser:"| @Char"`
+	Var     *string            `parser:"| '!' @Ident"`
 	Square  *SquareBracketExpr `parser:"| @@"`
 	Bracket *BracketedExpr     `parser:"| @@"`
 }
 
-// AsNode converts Expr to Node
-func (e *Expr) AsNode() *Node {
-	if e.Hex != nil {
-		return e.Hex.AsNode()
-	}
-	if e.Var != nil {
-		return e.Var.AsNode()
-	}
-	if e.Square != nil {
-		return e.Square.AsNode()
-	}
-	if e.Bracket != nil {
-		return e.Bracket.AsNode()
-	}
-	return nil
+// ExprElement represents either an expression or an operator. Inside brackets a
+// label or constant can be named without the '!'.
+type ExprElement struct {
+	Expr     *Expr   `parser:"  @@"`
+	Name     *string `parser:"| @Ident"`
+	Operator *string `parser:"| @('+' | '-' | '*' | '/' | '%' | '<<' | '>>' | '&' | '|' | '^' | '~')"`
 }
 
 type SquareBracketExpr struct {
-	Open     string         `parser:"'['"`
-	Elements []*ExprElement `parser:"@@ ( @@ )*"`
-	Close    string         `parser:"']'"`
-}
-
-// AsNode converts SquareBracketExpr to Node
-func (s *SquareBracketExpr) AsNode() *Node {
-	var elements []*Node
-	for _, elem := range s.Elements {
-		elements = append(elements, elem.AsNode())
-	}
-	node := &Node{
-		Type:  TypeSquareBracketExpr,
-		Value: elements,
-	}
-	return DisambiguateOrderOfOperations(node)
-}
-
-type Constant struct {
-	IsExport bool        `parser:"@('+' )?"`
-	Keyword  string      `parser:"@Constant"` // This matches the token `Constant`
-	Name     string      `parser:"@Ident"`
-	Value    *HexLiteral `parser:"'=' @@"`
-}
-
-// AsNode converts Constant to Node
-func (c *Constant) AsNode() *Node {
-	return &Node{
-		Type: TypeConstant,
-		Value: map[string]interface{}{
-			"isExport": c.IsExport,
-			"name":     c.Name,
-			"value":    c.Value.Value,
-		},
-	}
+	Elements []*ExprElement `parser:"'[' @@+ ']'"`
 }
 
 type BracketedExpr struct { //INFO: Representing the different states Ope, Closed and elems slice is for operators, brackets and elements
-	Open     string         `parser:"'('"`
-	Elements []*ExprElement `parser:"@@ ( @@ )*"`
-	Close    string         `parser:"')'"`
-}
-
-// AsNode converts BracketedExpr to Node
-func (b *BracketedExpr) AsNode() *Node {
-	var elements []*Node
-	for _, elem := range b.Elements {
-		elements = append(elements, elem.AsNode())
-	}
-	node := &Node{
-		Type:  TypeBracketedExpr,
-		Value: elements,
-	}
-	return DisambiguateOrderOfOperations(node)
+	Elements []*ExprElement `parser:"'(' @@+ ')'"`
 }
 
-type LitRegInstruction struct {
-	//Instr string `parser:"@(/[a-zA-Z]+/)"` WARN: Doesnt work
-	//	Instr string `parser:"@Instruction"` //WARN: Doesnt work
-	Instr string `parser:"@('MOV'|'mov'|'ADD'|'add'|'SUB'|'sub'|'MUL'|'mul'|'LSF'|'lsf'|'RSF'|'rsf'|'AND'|'and'|'OR'|'or'|'XOR'|'xor'|'JMP'|'jmp'|'JNE'|'jne'|'JEQ'|'jeq'|'JLT'|'jlt'|'JGT'|'jgt'|'JLE'|'jle'|'JGE'|'jge'|'PSH'|'psh'|'POP'|'pop'|'CAL'|'cal'|'RET'|'ret'|'HLT'|'hlt'|'INC'|'inc'|'DEC'|'dec'|'NOT'|'not')"`
-	//Instr string `parser:"@Ident"` // Generic identifier instead of hardcoded mnemonic WARN: Doesnt work
-	//Instr string    `parser:"@('add'|'ADD')"` WARN: When i use this it works
-	Arg1  *Expr     `parser:"@@"`
-	Comma string    `parser:"','"`
-	Arg2  *Register `parser:"@@"`
-}
+// === AST Nodes === //
+// positioner turns a position inside the statement into one in the source
+type positioner func(lexer.Position) Position
 
-// Fixed AsNode method with proper signature and variable references
-func (instr *LitRegInstruction) AsNode(instructionType string) *Node {
+func (d *LabelDecl) AsNode(at positioner) *Node {
 	return &Node{
-		Type: TypeInstruction,
-		Value: map[string]interface{}{
-			"instruction": instructionType,
-			"args":        []*Node{instr.Arg1.AsNode(), instr.Arg2.AsNode()},
-		},
+		Type:  TypeLabel,
+		Value: map[string]interface{}{"label": d.Name},
+		Pos:   at(d.Pos),
 	}
 }
 
-type RegRegInstruction struct {
-	Instr string `parser:"@('MOV'|'mov'|'ADD'|'add'|'SUB'|'sub'|'MUL'|'mul'|'LSF'|'lsf'|'RSF'|'rsf'|'AND'|'and'|'OR'|'or'|'XOR'|'xor'|'JMP'|'jmp'|'JNE'|'jne'|'JEQ'|'jeq'|'JLT'|'jlt'|'JGT'|'jgt'|'JLE'|'jle'|'JGE'|'jge'|'PSH'|'psh'|'POP'|'pop'|'CAL'|'cal'|'RET'|'ret'|'HLT'|'hlt'|'INC'|'inc'|'DEC'|'dec'|'NOT'|'not')"`
-	//Instr string    `parser:"@Ident"` // Generic identifier instead of hardcoded mnemonic
-	Reg1  *Register `parser:"@@"`
-	Comma string    `parser:"','"`
-	Reg2  *Register `parser:"@@"`
-}
-
-func (instr *RegRegInstruction) AsNode(instructionType string) *Node {
+func (c *ConstantDecl) AsNode(at positioner) *Node {
 	return &Node{
-		Type: TypeInstruction,
+		Type: TypeConstant,
 		Value: map[string]interface{}{
-			"instruction": instructionType,
-			"args":        []*Node{instr.Reg1.AsNode(), instr.Reg2.AsNode()},
+			"isExport": c.IsExport,
+			"name":     c.Name,
+			"value":    c.Value.AsNode(),
 		},
+		Pos: at(c.Pos),
 	}
 }
 
-type RegMemInstruction struct {
-	Instr  string           `parser:"@('MOV'|'mov'|'ADD'|'add'|'SUB'|'sub'|'MUL'|'mul'|'LSF'|'lsf'|'RSF'|'rsf'|'AND'|'and'|'OR'|'or'|'XOR'|'xor'|'JMP'|'jmp'|'JNE'|'jne'|'JEQ'|'jeq'|'JLT'|'jlt'|'JGT'|'jgt'|'JLE'|'jle'|'JGE'|'jge'|'PSH'|'psh'|'POP'|'pop'|'CAL'|'cal'|'RET'|'ret'|'HLT'|'hlt'|'INC'|'inc'|'DEC'|'dec'|'NOT'|'not')"`
-	Reg    *Register        `parser:"@@"`
-	Comma  string           `parser:"','"`
-	Memory *MemoryReference `parser:"@@"`
-}
-
-func (instr *RegMemInstruction) AsNode(instructionType string) *Node {
-	return &Node{
-		Type: TypeInstruction,
-		Value: map[string]interface{}{
-			"instruction": instructionType,
-			"args":        []*Node{instr.Reg.AsNode(), instr.Memory.AsNode()},
-		},
+func (d *DataDecl) AsNode(at positioner) *Node {
+	values := make([]*Node, 0, len(d.Values))
+	for _, v := range d.Values {
+		var node *Node
+		if v.String != nil {
+			node = &Node{Type: TypeStringLiteral, Value: *v.String}
+		} else {
+			node = v.Value.AsNode()
+		}
+		node.Pos = at(v.Pos)
+		values = append(values, node)
 	}
-}
 
-type MemRegInstruction struct {
-	Instr  string           `parser:"@('MOV'|'mov'|'ADD'|'add'|'SUB'|'sub'|'MUL'|'mul'|'LSF'|'lsf'|'RSF'|'rsf'|'AND'|'and'|'OR'|'or'|'XOR'|'xor'|'JMP'|'jmp'|'JNE'|'jne'|'JEQ'|'jeq'|'JLT'|'jlt'|'JGT'|'jgt'|'JLE'|'jle'|'JGE'|'jge'|'PSH'|'psh'|'POP'|'pop'|'CAL'|'cal'|'RET'|'ret'|'HLT'|'hlt'|'INC'|'inc'|'DEC'|'dec'|'NOT'|'not')"`
-	Memory *MemoryReference `parser:"@@"`
-	Comma  string           `parser:"','"`
-	Reg    *Register        `parser:"@@"`
-}
-
-func (instr *MemRegInstruction) AsNode(instructionType string) *Node {
-	return &Node{
-		Type: TypeInstruction,
-		Value: map[string]interface{}{
-			"instruction": instructionType,
-			"args":        []*Node{instr.Memory.AsNode(), instr.Reg.AsNode()},
-		},
+	size := 8
+	if d.DataType == "data16" {
+		size = 16
 	}
-}
-
-type LitMemInstruction struct {
-	Instr  string            `parser:"@('MOV'|'mov'|'ADD'|'add'|'SUB'|'sub'|'MUL'|'mul'|'LSF'|'lsf'|'RSF'|'rsf'|'AND'|'and'|'OR'|'or'|'XOR'|'xor'|'JMP'|'jmp'|'JNE'|'jne'|'JEQ'|'jeq'|'JLT'|'jlt'|'JGT'|'jgt'|'JLE'|'jle'|'JGE'|'jge'|'PSH'|'psh'|'POP'|'pop'|'CAL'|'cal'|'RET'|'ret'|'HLT'|'hlt'|'INC'|'inc'|'DEC'|'dec'|'NOT'|'not')"`
-	Lit    *LiteralReference `parser:"@@"`
-	Comma  string            `parser:"','"`
-	Memory *MemoryReference  `parser:"@@"`
-}
-
-func (instr *LitMemInstruction) AsNode(instructionType string) *Node {
 	return &Node{
-		Type: TypeInstruction,
+		Type: TypeData,
 		Value: map[string]interface{}{
-			"instruction": instructionType,
-			"args":        []*Node{instr.Lit.AsNode(), instr.Memory.AsNode()},
+			"size":     size,
+			"isExport": d.IsExport,
+			"name":     d.Name,
+			"values":   values,
 		},
+		Pos: at(d.Pos),
 	}
 }
 
-type RegPtrToRegInstruction struct {
-	Instr  string           `parser:"@('MOV'|'mov'|'ADD'|'add'|'SUB'|'sub'|'MUL'|'mul'|'LSF'|'lsf'|'RSF'|'rsf'|'AND'|'and'|'OR'|'or'|'XOR'|'xor'|'JMP'|'jmp'|'JNE'|'jne'|'JEQ'|'jeq'|'JLT'|'jlt'|'JGT'|'jgt'|'JLE'|'jle'|'JGE'|'jge'|'PSH'|'psh'|'POP'|'pop'|'CAL'|'cal'|'RET'|'ret'|'HLT'|'hlt'|'INC'|'inc'|'DEC'|'dec'|'NOT'|'not')"`
-	RegPtr *RegisterPointer `parser:"@@"`
-	Comma  string           `parser:"','"`
-	Reg    *Register        `parser:"@@"`
-}
-
-func (instr *RegPtrToRegInstruction) AsNode(instructionType string) *Node {
-	return &Node{
-		Type: TypeInstruction,
-		Value: map[string]interface{}{
-			"instruction": instructionType,
-			"args":        []*Node{instr.RegPtr.AsNode(), instr.Reg.AsNode()},
-		},
+func (d *DirectiveStmt) AsNode(at positioner) *Node {
+	args := make([]*Node, 0, len(d.Arguments))
+	for _, arg := range d.Arguments {
+		node := arg.Value.AsNode()
+		node.Pos = at(arg.Pos)
+		args = append(args, node)
 	}
-}
-
-type ConstToRegInstruction struct {
-	Instr  string           `parser:"@('MOV'|'mov'|'ADD'|'add'|'SUB'|'sub'|'MUL'|'mul'|'LSF'|'lsf'|'RSF'|'rsf'|'AND'|'and'|'OR'|'or'|'XOR'|'xor'|'JMP'|'jmp'|'JNE'|'jne'|'JEQ'|'jeq'|'JLT'|'jlt'|'JGT'|'jgt'|'JLE'|'jle'|'JGE'|'jge'|'PSH'|'psh'|'POP'|'pop'|'CAL'|'cal'|'RET'|'ret'|'HLT'|'hlt'|'INC'|'inc'|'DEC'|'dec'|'NOT'|'not')"`
-	Const  *Constant        `parser:"@@"`
-	Comma1 string           `parser:"','"`
-	RegPtr *RegisterPointer `parser:"@@"`
-	Comma2 string           `parser:"','"`
-	Reg    *Register        `parser:"@@"`
-}
-
-func (instr *ConstToRegInstruction) AsNode(instructionType string) *Node {
 	return &Node{
-		Type: TypeInstruction,
+		Type: TypeDirective,
 		Value: map[string]interface{}{
-			"instruction": instructionType,
-			"args":        []*Node{instr.Const.AsNode(), instr.RegPtr.AsNode(), instr.Reg.AsNode()},
+			"directive": d.Directive,
+			"args":      args,
 		},
+		Pos: at(d.Pos),
 	}
 }
 
-type LitOffToRegInstruction struct {
-	Instr  string            `parser:"@('MOV'|'mov'|'ADD'|'add'|'SUB'|'sub'|'MUL'|'mul'|'LSF'|'lsf'|'RSF'|'rsf'|'AND'|'and'|'OR'|'or'|'XOR'|'xor'|'JMP'|'jmp'|'JNE'|'jne'|'JEQ'|'jeq'|'JLT'|'jlt'|'JGT'|'jgt'|'JLE'|'jle'|'JGE'|'jge'|'PSH'|'psh'|'POP'|'pop'|'CAL'|'cal'|'RET'|'ret'|'HLT'|'hlt'|'INC'|'inc'|'DEC'|'dec'|'NOT'|'not')"`
-	Lit    *LiteralReference `parser:"@@"`
-	Comma1 string            `parser:"','"`
-	RegPtr *RegisterPointer  `parser:"@@"`
-	Comma2 string            `parser:"','"`
-	Reg    *Register         `parser:"@@"`
-}
-
-func (instr *LitOffToRegInstruction) AsNode(instructionType string) *Node {
-	return &Node{
-		Type: TypeInstruction,
-		Value: map[string]interface{}{
-			"instruction": instructionType,
-			"args":        []*Node{instr.Lit.AsNode(), instr.RegPtr.AsNode(), instr.Reg.AsNode()},
-		},
+// AsNode converts the operand, registers and addresses get their own node
+// types and anything else is a literal expression
+func (o *Operand) AsNode(at positioner) *Node {
+	var node *Node
+	switch {
+	case o.Pointer != nil:
+		node = &Node{Type: TypeRegisterPointer, Value: *o.Pointer}
+	case o.Address != nil && o.Address.Expression != nil:
+		// For expressions, create a memory reference node that wraps the square bracket expression
+		node = &Node{Type: TypeMemoryReference, Value: o.Address.Expression.AsNode()}
+	case o.Address != nil:
+		node = &Node{Type: TypeAddress, Value: o.Address.Hex}
+	case o.Register != nil:
+		node = &Node{Type: TypeRegister, Value: *o.Register}
+	default:
+		node = o.Literal.AsNode()
 	}
+	node.Pos = at(o.Pos)
+	return node
 }
 
-type NoArgsInstruction struct {
-	Instr string `parser:"@('MOV'|'mov'|'ADD'|'add'|'SUB'|'sub'|'MUL'|'mul'|'LSF'|'lsf'|'RSF'|'rsf'|'AND'|'and'|'OR'|'or'|'XOR'|'xor'|'JMP'|'jmp'|'JNE'|'jne'|'JEQ'|'jeq'|'JLT'|'jlt'|'JGT'|'jgt'|'JLE'|'jle'|'JGE'|'jge'|'PSH'|'psh'|'POP'|'pop'|'CAL'|'cal'|'RET'|'ret'|'HLT'|'hlt'|'INC'|'inc'|'DEC'|'dec'|'NOT'|'not')"`
-}
-
-func (instr *NoArgsInstruction) AsNode(instructionType string) *Node {
-	return &Node{
-		Type: TypeInstruction,
-		Value: map[string]interface{}{
-			"instruction": instructionType,
-			"args":        []*Node{},
-		},
+// AsNode converts Expr to Node
+func (e *Expr) AsNode() *Node {
+	switch {
+	case e.Hex != nil:
+		return &Node{Type: TypeHexLiteral, Value: *e.Hex}
+	case e.Decimal != nil:
+		return &Node{Type: TypeDecimalLiteral, Value: *e.Decimal}
+	case e.Binary != nil:
+		return &Node{Type: TypeBinaryLiteral, Value: *e.Binary}
+	case e.Char != nil:
+		return &Node{Type: TypeCharLiteral, Value: *e.Char}
+	case e.Var != nil:
+		return &Node{Type: TypeVariable, Value: *e.Var}
+	case e.Square != nil:
+		return e.Square.AsNode()
+	case e.Bracket != nil:
+		return e.Bracket.AsNode()
 	}
+	return nil
 }
 
-// NoArgInstruction represents instructions without arguments (like HLT, RET)
-type NoArgInstruction struct {
-	Instr string `parser:"@('MOV'|'mov'|'ADD'|'add'|'SUB'|'sub'|'MUL'|'mul'|'LSF'|'lsf'|'RSF'|'rsf'|'AND'|'and'|'OR'|'or'|'XOR'|'xor'|'JMP'|'jmp'|'JNE'|'jne'|'JEQ'|'jeq'|'JLT'|'jlt'|'JGT'|'jgt'|'JLE'|'jle'|'JGE'|'jge'|'PSH'|'psh'|'POP'|'pop'|'CAL'|'cal'|'RET'|'ret'|'HLT'|'hlt'|'INC'|'inc'|'DEC'|'dec'|'NOT'|'not')"`
-}
-
-type SingleRegInstruction struct {
-	Instr string    `parser:"@('MOV'|'mov'|'ADD'|'add'|'SUB'|'sub'|'MUL'|'mul'|'LSF'|'lsf'|'RSF'|'rsf'|'AND'|'and'|'OR'|'or'|'XOR'|'xor'|'JMP'|'jmp'|'JNE'|'jne'|'JEQ'|'jeq'|'JLT'|'jlt'|'JGT'|'jgt'|'JLE'|'jle'|'JGE'|'jge'|'PSH'|'psh'|'POP'|'pop'|'CAL'|'cal'|'RET'|'ret'|'HLT'|'hlt'|'INC'|'inc'|'DEC'|'dec'|'NOT'|'not')"`
-	Reg   *Register `parser:"@@"`
+var operatorTypes = map[string]NodeType{
+	"+":  TypeOpPlus,
+	"-":  TypeOpMinus,
+	"*":  TypeOpMultiply,
+	"/":  TypeOpDivide,
+	"%":  TypeOpModulo,
+	"<<": TypeOpShiftLeft,
+	">>": TypeOpShiftRight,
+	"&":  TypeOpAnd,
+	"|":  TypeOpOr,
+	"^":  TypeOpXor,
+	"~":  TypeOpNot,
 }
 
-func (instr *SingleRegInstruction) AsNode(instructionType string) *Node {
-	return &Node{
-		Type: TypeInstruction,
-		Value: map[string]interface{}{
-			"instruction": instructionType,
-			"args":        []*Node{instr.Reg.AsNode()},
-		},
+// AsNode converts ExprElement to Node
+func (e *ExprElement) AsNode() *Node {
+	switch {
+	case e.Expr != nil:
+		return e.Expr.AsNode()
+	case e.Name != nil:
+		return &Node{Type: TypeVariable, Value: *e.Name}
 	}
-
+	return &Node{Type: operatorTypes[*e.Operator], Value: *e.Operator}
 }
 
-type SingleLitInstruction struct {
-	Instr string            `parser:"@('MOV'|'mov'|'ADD'|'add'|'SUB'|'sub'|'MUL'|'mul'|'LSF'|'lsf'|'RSF'|'rsf'|'AND'|'and'|'OR'|'or'|'XOR'|'xor'|'JMP'|'jmp'|'JNE'|'jne'|'JEQ'|'jeq'|'JLT'|'jlt'|'JGT'|'jgt'|'JLE'|'jle'|'JGE'|'jge'|'PSH'|'psh'|'POP'|'pop'|'CAL'|'cal'|'RET'|'ret'|'HLT'|'hlt'|'INC'|'inc'|'DEC'|'dec'|'NOT'|'not')"`
-	Lit   *LiteralReference `parser:"@@"`
+// AsNode converts SquareBracketExpr to Node
+func (s *SquareBracketExpr) AsNode() *Node {
+	return DisambiguateOrderOfOperations(&Node{Type: TypeSquareBracketExpr, Value: elementNodes(s.Elements)})
 }
 
-func (instr *SingleLitInstruction) AsNode(instructionType string) *Node {
-	return &Node{
-		Type: TypeInstruction,
-		Value: map[string]interface{}{
-			"instruction": instructionType,
-			"args":        []*Node{instr.Lit.AsNode()},
-		},
-	}
-
+// AsNode converts BracketedExpr to Node
+func (b *BracketedExpr) AsNode() *Node {
+	return DisambiguateOrderOfOperations(&Node{Type: TypeBracketedExpr, Value: elementNodes(b.Elements)})
 }
 
-// BinaryOperation represents a binary operation with left operand, operator, and right operand
-type BinaryOperation struct {
-	A  *Node
-	Op *Node
-	B  *Node
+func elementNodes(elements []*ExprElement) []*Node {
+	nodes := make([]*Node, 0, len(elements))
+	for _, elem := range elements {
+		nodes = append(nodes, elem.AsNode())
+	}
+	return nodes
 }
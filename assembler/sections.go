@@ -0,0 +1,130 @@
+package assembler
+
+import (
+	"sort"
+
+	"github.com/martbul/assembler/parser"
+)
+
+// addressSpace is the number of addressable bytes
+const addressSpace = 0x10000
+
+// Section is a contiguous run of assembled bytes
+type Section struct {
+	Address int
+	Code    []byte
+}
+
+// section is a Section while it is being assembled
+type section struct {
+	Section
+	fill byte            // fills the gap in front of the section in the image
+	pos  parser.Position // org that started the section
+}
+
+// placement is where a directive leaves the location counter
+type placement struct {
+	address int
+	fill    byte
+}
+
+// directive handles org, align and reserve/ds in the first pass and returns
+// the new location counter. Their arguments must be known at this point, so
+// they can use constants and earlier labels but not later ones.
+func (a *assembly) directive(node *parser.Node, address int) int {
+	value := node.Value.(map[string]interface{})
+	name := value["directive"].(string)
+	args := value["args"].([]*parser.Node)
+
+	usage, limit := name+" takes an address and an optional fill byte", 2
+	switch name {
+	case "align":
+		usage = "align takes a boundary and an optional fill byte"
+	case "reserve", "ds":
+		usage, limit = name+" takes a size", 1
+	}
+	if len(args) > limit {
+		a.errorf("%s", usage)
+		return address
+	}
+
+	target := a.value(args[0], 16)
+	var p placement
+	if len(args) == 2 {
+		p.fill = byte(a.value(args[1], 8))
+	}
+
+	switch name {
+	case "org":
+		p.address = target
+	case "align":
+		if target == 0 {
+			a.errorAt(args[0], "cannot align to 0")
+			return address
+		}
+		p.address = (address + target - 1) / target * target
+	default:
+		p.address = address + target
+	}
+
+	a.layout[node] = p
+	return p.address
+}
+
+// place moves to where the directive left the location counter in the first pass
+func (a *assembly) place(node *parser.Node) {
+	p := a.layout[node]
+	if node.Value.(map[string]interface{})["directive"] == "org" {
+		a.sections = append(a.sections, &section{Section: Section{Address: p.address}, fill: p.fill, pos: node.Pos})
+		return
+	}
+	current := a.sections[len(a.sections)-1]
+	for current.Address+len(current.Code) < p.address {
+		current.Code = append(current.Code, p.fill)
+	}
+}
+
+// emit appends bytes to the current section
+func (a *assembly) emit(bytes ...byte) {
+	current := a.sections[len(a.sections)-1]
+	current.Code = append(current.Code, bytes...)
+}
+
+// link orders the sections, checks that they don't overlap and builds the
+// image that starts at the lowest section. Gaps between sections are filled
+// with the fill byte of the section after the gap.
+func (a *assembly) link() ([]Section, []byte, int) {
+	var used []*section
+	for _, s := range a.sections {
+		if len(s.Code) > 0 {
+			used = append(used, s)
+		}
+	}
+	if len(used) == 0 {
+		return nil, nil, 0
+	}
+	sort.SliceStable(used, func(i, j int) bool { return used[i].Address < used[j].Address })
+
+	origin := used[0].Address
+	var sections []Section
+	var image []byte
+	for i, s := range used {
+		if i > 0 {
+			previous := used[i-1]
+			if end := previous.Address + len(previous.Code); s.Address < end {
+				pos := s.pos
+				if pos.Line == 0 {
+					pos = previous.pos // code in front of the first org
+				}
+				a.report(SeverityError, pos, "code at 0x%04X overlaps the section at 0x%04X-0x%04X", s.Address, previous.Address, end-1)
+				continue
+			}
+		}
+		for origin+len(image) < s.Address {
+			image = append(image, s.fill)
+		}
+		image = append(image, s.Code...)
+		sections = append(sections, s.Section)
+	}
+	return sections, image, origin
+}
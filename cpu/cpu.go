@@ -26,6 +26,12 @@ type CPU struct {
 	stackFrameSize        int
 	interuptVectorAddress int
 	isInInteruptedHandler bool
+	pendingInterupts      []uint16
+	cycles                uint64
+	haltReason            string
+	exitStatus            uint16
+	poweredOff            bool
+	entryPoint            uint16 // ip after a reset, see SetEntryPoint
 }
 
 func NewCPU(mem *memorymapper.MemoryMapper, interuptVectorAddress ...int) *CPU {
@@ -52,14 +58,58 @@ func NewCPU(mem *memorymapper.MemoryMapper, interuptVectorAddress ...int) *CPU {
 		interuptVectorAddress: vector,
 		isInInteruptedHandler: false,
 	}
+	cpu.resetRegisters()
+
+	return cpu
+}
+
+// Reset puts the registers, the interupt state and the halt state back to how NewCPU creates them and resets the
+// mapped devices, so a halted or powered off cpu runs again. Memory is left untouched
+func (cpu *CPU) Reset() {
+	cpu.resetRegisters()
+	cpu.haltReason = ""
+	cpu.exitStatus, cpu.poweredOff = 0, false
+	cpu.memory.ResetDevices()
+}
+
+func (cpu *CPU) resetRegisters() {
+	for i := range cpu.registers.GetBuffer() {
+		cpu.registers.SetUint8(i, 0)
+	}
+	cpu.stackFrameSize = 0
+	cpu.isInInteruptedHandler = false
+	cpu.pendingInterupts = nil
 
 	// Stack grows downward, so set SP and FP at the end of memory
 	cpu.SetRegister("sp", 0xffff-1)
 	cpu.SetRegister("fp", 0xffff-1)
 
 	cpu.SetRegister("im", 0xffff)
+	cpu.SetRegister("ip", cpu.entryPoint)
+}
 
-	return cpu
+// SetEntryPoint moves ip to address and makes every reset start the program there again,
+// e.g. for a program assembled with org that is not loaded at 0
+func (cpu *CPU) SetEntryPoint(address uint16) {
+	cpu.entryPoint = address
+	cpu.SetRegister("ip", address)
+}
+
+// Halt stops the cpu before the next instruction, Step returns the reason
+func (cpu *CPU) Halt(reason string) {
+	cpu.haltReason = reason
+}
+
+// PowerOff halts the cpu and records the exit status for go code (see ExitStatus)
+func (cpu *CPU) PowerOff(status uint16) {
+	cpu.exitStatus = status
+	cpu.poweredOff = true
+	cpu.Halt("power off")
+}
+
+// ExitStatus returns the status passed to PowerOff, ok is false when the cpu was never powered off
+func (cpu *CPU) ExitStatus() (status uint16, ok bool) {
+	return cpu.exitStatus, cpu.poweredOff
 }
 
 func (cpu *CPU) Debug() {
@@ -351,7 +401,7 @@ func (cpu *CPU) Execute(instr uint8) (bool, string) {
 	//in left shif 9 << 2 is equal to 9 * 2^2
 	case instructions.LSF_REG_LIT:
 		r1 := cpu.FetachRegisterIndex()
-		literal := cpu.Fetch16()
+		literal := uint16(cpu.Fetch())
 		registerValue := cpu.registers.GetUint16(r1)
 		res := registerValue << literal
 		cpu.registers.SetUint16(r1, res)
@@ -370,7 +420,7 @@ func (cpu *CPU) Execute(instr uint8) (bool, string) {
 	//INFO: in right shift 9 >> 2 is equal to 9 / 2^2
 	case instructions.RSF_REG_LIT:
 		r1 := cpu.FetachRegisterIndex()
-		literal := cpu.Fetch16()
+		literal := uint16(cpu.Fetch())
 		registerValue := cpu.registers.GetUint16(r1)
 		res := registerValue >> literal
 		cpu.registers.SetUint16(r1, res)
@@ -635,11 +685,39 @@ func (cpu *CPU) Execute(instr uint8) (bool, string) {
 }
 
 func (cpu *CPU) Step() (bool, string) {
+	if cpu.haltReason != "" {
+		return true, cpu.haltReason
+	}
+
+	//INFO: hardware interupts are only serviced between instructions and never inside of another handler
+	if !cpu.isInInteruptedHandler && len(cpu.pendingInterupts) > 0 {
+		value := cpu.pendingInterupts[0]
+		cpu.pendingInterupts = cpu.pendingInterupts[1:]
+		cpu.HandleInterupt(value)
+	}
+
 	instruction := cpu.Fetch()
 	isRunning, hltReason := cpu.Execute(instruction)
+
+	//INFO: every executed instruction counts as one cycle, which keeps device timing deterministic
+	cpu.cycles++
+	cpu.memory.TickDevices(1)
+	if !isRunning && cpu.haltReason != "" {
+		return true, cpu.haltReason
+	}
 	return isRunning, hltReason
 }
 
+// GetCycles returns the number of instructions executed since the cpu was created
+func (cpu *CPU) GetCycles() uint64 {
+	return cpu.cycles
+}
+
+// RequestInterupt queues a hardware interupt, it is handled before the next instruction once the cpu is not in a handler
+func (cpu *CPU) RequestInterupt(value uint16) {
+	cpu.pendingInterupts = append(cpu.pendingInterupts, value)
+}
+
 func (cpu *CPU) Run() {
 	for {
 		isRunning, _ := cpu.Step()
@@ -0,0 +1,87 @@
+package cpu
+
+import (
+	"testing"
+
+	"github.com/martbul/instructions"
+	"github.com/martbul/memory"
+	memorymapper "github.com/martbul/memoryMapper"
+	"github.com/martbul/registers"
+)
+
+// newTestCPU creates a cpu with 64k of ram holding program at address 0
+func newTestCPU(program ...byte) *CPU {
+	ram := memory.CreateMemory(0x10000)
+	copy(ram.GetBuffer(), program)
+	mapper := memorymapper.NewMemoryMapper()
+	mapper.Map(ram, 0, 0xffff)
+	return NewCPU(mapper)
+}
+
+// setR1 sets r1 to 0x1234 and halts
+var setR1 = []byte{instructions.MOV_LIT_REG, 0x12, 0x34, byte(registers.Map["r1"]), instructions.HLT}
+
+func TestResetAfterHalt(t *testing.T) {
+	for _, stop := range []struct {
+		name string
+		stop func(cpu *CPU)
+	}{
+		{"halt", func(cpu *CPU) { cpu.Halt("watchdog timeout") }},
+		{"power off", func(cpu *CPU) { cpu.PowerOff(3) }},
+	} {
+		t.Run(stop.name, func(t *testing.T) {
+			cpu := newTestCPU(setR1...)
+			stop.stop(cpu)
+			if halted, _ := cpu.Step(); !halted {
+				t.Fatal("the cpu kept running")
+			}
+
+			cpu.Reset()
+			if _, ok := cpu.ExitStatus(); ok {
+				t.Error("the exit status survived the reset")
+			}
+			cpu.Run()
+			if r1 := cpu.GetRegister("r1"); r1 != 0x1234 {
+				t.Errorf("r1 = %04X after the reset, want 1234", r1)
+			}
+		})
+	}
+}
+
+func TestPowerOffStatus(t *testing.T) {
+	cpu := newTestCPU(setR1...)
+	if _, ok := cpu.ExitStatus(); ok {
+		t.Error("a new cpu reports an exit status")
+	}
+	cpu.PowerOff(7)
+	if status, ok := cpu.ExitStatus(); !ok || status != 7 {
+		t.Errorf("ExitStatus = %d, %v, want 7, true", status, ok)
+	}
+	if halted, reason := cpu.Step(); !halted || reason != "power off" {
+		t.Errorf("Step = %v, %q, want true, \"power off\"", halted, reason)
+	}
+}
+
+// hookCounter counts the lifecycle calls it gets from the cpu
+type hookCounter struct {
+	*memory.DataView
+	resets, cycles int
+}
+
+func (h *hookCounter) Reset()          { h.resets++ }
+func (h *hookCounter) Tick(cycles int) { h.cycles += cycles }
+
+func TestDeviceHooks(t *testing.T) {
+	cpu := newTestCPU(setR1...)
+	hooks := &hookCounter{DataView: memory.CreateMemory(2)}
+	cpu.memory.Map(hooks, 0xfffe, 0xffff)
+
+	cpu.Run()
+	if hooks.cycles != 2 || cpu.GetCycles() != 2 {
+		t.Errorf("devices ticked %d cycles, cpu counted %d, want 2 instructions", hooks.cycles, cpu.GetCycles())
+	}
+	cpu.Reset()
+	if hooks.resets != 1 {
+		t.Errorf("devices reset %d times, want 1", hooks.resets)
+	}
+}
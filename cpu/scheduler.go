@@ -0,0 +1,63 @@
+package cpu
+
+//INFO: The scheduler runs several cpus in the same process, e.g. vms that talk through a mailbox device.
+// In lockstep mode every cpu executes one instruction per round, in round robin mode every cpu executes Quantum instructions before the next one gets its turn.
+
+type ScheduleMode int
+
+const (
+	Lockstep ScheduleMode = iota
+	RoundRobin
+)
+
+type Scheduler struct {
+	Mode    ScheduleMode
+	Quantum int // instructions per turn in RoundRobin mode
+	cpus    []*CPU
+	halted  []bool
+}
+
+func NewScheduler(mode ScheduleMode, cpus ...*CPU) *Scheduler {
+	return &Scheduler{
+		Mode:    mode,
+		Quantum: 100,
+		cpus:    cpus,
+		halted:  make([]bool, len(cpus)),
+	}
+}
+
+// Add puts another cpu under the scheduler's control
+func (s *Scheduler) Add(cpu *CPU) {
+	s.cpus = append(s.cpus, cpu)
+	s.halted = append(s.halted, false)
+}
+
+// Round gives every running cpu one turn, it returns false once all cpus halted
+func (s *Scheduler) Round() bool {
+	steps := 1
+	if s.Mode == RoundRobin && s.Quantum > 0 {
+		steps = s.Quantum
+	}
+
+	running := false
+	for i, cpu := range s.cpus {
+		for n := 0; n < steps && !s.halted[i]; n++ {
+			s.halted[i], _ = cpu.Step()
+		}
+		if !s.halted[i] {
+			running = true
+		}
+	}
+	return running
+}
+
+// Run schedules the cpus until every one of them halted
+func (s *Scheduler) Run() {
+	for s.Round() {
+	}
+}
+
+// IsHalted reports whether the i-th cpu halted
+func (s *Scheduler) IsHalted(i int) bool {
+	return s.halted[i]
+}
@@ -0,0 +1,262 @@
+package devices
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	memorymapper "github.com/martbul/memoryMapper"
+)
+
+//INFO: Block storage backed by a host disk image.
+// Transfers always move a whole sector, either into the sector window of the device or (dma) straight into guest ram.
+//
+// Register layout (16 bit registers, offsets relative to the mapped start):
+//
+//	+0x00 COMMAND  writing starts a transfer: 1 read, 2 write, 3 dma read, 4 dma write, 5 flush
+//	+0x02 SECTOR   sector number used by the next command
+//	+0x04 BUFFER   guest address used by the dma commands
+//	+0x06 STATUS   bit0 done, bit1 error, bit2 read-only media
+//	+0x08 SECTORS  number of sectors in the image (read-only)
+//	+0x100 - +0x1FF the sector window, read/write commands copy between the disk and this window
+
+const (
+	BlockSectorSize = 0x100
+	BlockSize       = 0x200
+
+	BlockCmdRead     = 1
+	BlockCmdWrite    = 2
+	BlockCmdDMARead  = 3
+	BlockCmdDMAWrite = 4
+	BlockCmdFlush    = 5
+
+	BlockStatusDone     = 1 << 0
+	BlockStatusError    = 1 << 1
+	BlockStatusReadOnly = 1 << 2
+
+	blockCommand = 0x00
+	blockSector  = 0x02
+	blockBuffer  = 0x04
+	blockStatus  = 0x06
+	blockSectors = 0x08
+	blockWindow  = 0x100
+)
+
+// BlockMode selects how writes reach the image file
+type BlockMode int
+
+const (
+	BlockReadWrite   BlockMode = iota
+	BlockReadOnly              // writes fail with the error bit
+	BlockCopyOnWrite           // writes are kept in memory, the image file is never modified
+)
+
+// BlockDevice is a sector addressed storage device
+type BlockDevice struct {
+	file    *os.File
+	mode    BlockMode
+	sectors int
+	overlay map[int][]byte // copy-on-write sectors
+	memory  *memorymapper.MemoryMapper
+
+	sector uint16
+	buffer uint16
+	status uint16
+	window []byte
+	latch  registerLatch
+}
+
+// OpenBlockDevice opens the image at path. memory is used by the dma commands and may be nil
+func OpenBlockDevice(path string, mode BlockMode, memory *memorymapper.MemoryMapper) (*BlockDevice, error) {
+	flag := os.O_RDWR
+	if mode != BlockReadWrite {
+		flag = os.O_RDONLY
+	}
+	file, err := os.OpenFile(path, flag, 0)
+	if err != nil {
+		return nil, fmt.Errorf("block device: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("block device: %w", err)
+	}
+
+	sectors := int((info.Size() + BlockSectorSize - 1) / BlockSectorSize)
+	if sectors > 0xffff {
+		file.Close()
+		return nil, fmt.Errorf("block device: image %s has more than 65535 sectors", path)
+	}
+
+	return &BlockDevice{
+		file:    file,
+		mode:    mode,
+		sectors: sectors,
+		overlay: make(map[int][]byte),
+		memory:  memory,
+		window:  make([]byte, BlockSectorSize),
+	}, nil
+}
+
+// Close releases the image file
+func (b *BlockDevice) Close() error {
+	return b.file.Close()
+}
+
+// Reset clears the registers and the sector window, the image (and copy-on-write sectors) stay as they are
+func (b *BlockDevice) Reset() {
+	b.sector = 0
+	b.buffer = 0
+	b.status = 0
+	clear(b.window)
+	b.latch = registerLatch{}
+}
+
+func (b *BlockDevice) GetUint16(address int) uint16 {
+	if address >= blockWindow {
+		return uint16(b.GetUint8(address))<<8 | uint16(b.GetUint8(address+1))
+	}
+
+	switch address &^ 1 {
+	case blockSector:
+		return b.sector
+	case blockBuffer:
+		return b.buffer
+	case blockStatus:
+		status := b.status
+		if b.mode == BlockReadOnly {
+			status |= BlockStatusReadOnly
+		}
+		return status
+	case blockSectors:
+		return uint16(b.sectors)
+	}
+	return 0
+}
+
+func (b *BlockDevice) SetUint16(address int, value uint16) {
+	if address >= blockWindow {
+		b.SetUint8(address, uint8(value>>8))
+		b.SetUint8(address+1, uint8(value))
+		return
+	}
+
+	switch address &^ 1 {
+	case blockCommand:
+		b.execute(value)
+	case blockSector:
+		b.sector = value
+	case blockBuffer:
+		b.buffer = value
+	}
+}
+
+func (b *BlockDevice) GetUint8(address int) uint8 {
+	if address >= blockWindow {
+		return b.window[(address-blockWindow)%BlockSectorSize]
+	}
+	return registerByte(b, address)
+}
+
+func (b *BlockDevice) SetUint8(address int, value uint8) {
+	if address >= blockWindow {
+		b.window[(address-blockWindow)%BlockSectorSize] = value
+		return
+	}
+	if address&^1 == blockCommand {
+		b.latch.writeByte(b, address, value) // the command runs once the low byte is written
+		return
+	}
+	setRegisterByte(b, address, value)
+}
+
+func (b *BlockDevice) execute(command uint16) {
+	var err error
+	switch command {
+	case BlockCmdRead:
+		err = b.readSector(int(b.sector), b.window)
+	case BlockCmdWrite:
+		err = b.writeSector(int(b.sector), b.window)
+	case BlockCmdDMARead:
+		data := make([]byte, BlockSectorSize)
+		if err = b.readSector(int(b.sector), data); err == nil {
+			err = b.copyToMemory(data)
+		}
+	case BlockCmdDMAWrite:
+		data := make([]byte, BlockSectorSize)
+		if err = b.copyFromMemory(data); err == nil {
+			err = b.writeSector(int(b.sector), data)
+		}
+	case BlockCmdFlush:
+		if b.mode == BlockReadWrite {
+			err = b.file.Sync()
+		}
+	default:
+		err = fmt.Errorf("unknown command %d", command)
+	}
+
+	if err != nil {
+		b.status = BlockStatusDone | BlockStatusError
+		return
+	}
+	b.status = BlockStatusDone
+}
+
+func (b *BlockDevice) readSector(sector int, data []byte) error {
+	if sector >= b.sectors {
+		return fmt.Errorf("sector %d out of range", sector)
+	}
+	if overlay, ok := b.overlay[sector]; ok {
+		copy(data, overlay)
+		return nil
+	}
+
+	n, err := b.file.ReadAt(data, int64(sector)*BlockSectorSize)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	clear(data[n:]) // the last sector of the image may be partial
+	return nil
+}
+
+func (b *BlockDevice) writeSector(sector int, data []byte) error {
+	if sector >= b.sectors {
+		return fmt.Errorf("sector %d out of range", sector)
+	}
+
+	switch b.mode {
+	case BlockReadOnly:
+		return fmt.Errorf("read-only media")
+	case BlockCopyOnWrite:
+		b.overlay[sector] = append([]byte(nil), data...)
+		return nil
+	}
+	_, err := b.file.WriteAt(data, int64(sector)*BlockSectorSize)
+	return err
+}
+
+func (b *BlockDevice) copyToMemory(data []byte) error {
+	if b.memory == nil {
+		return fmt.Errorf("no memory attached for dma")
+	}
+	for i, value := range data {
+		if err := b.memory.SetUint8(int(b.buffer)+i, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *BlockDevice) copyFromMemory(data []byte) error {
+	if b.memory == nil {
+		return fmt.Errorf("no memory attached for dma")
+	}
+	for i := range data {
+		value, err := b.memory.GetUint8(int(b.buffer) + i)
+		if err != nil {
+			return err
+		}
+		data[i] = value
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package devices
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/martbul/memory"
+	memorymapper "github.com/martbul/memoryMapper"
+)
+
+// blockImage writes an image of two and a half sectors, where byte i of the
+// image holds i plus its sector number, and returns its path
+func blockImage(t *testing.T) string {
+	t.Helper()
+	image := make([]byte, 2*BlockSectorSize+0x80)
+	for i := range image {
+		image[i] = byte(i/BlockSectorSize + i)
+	}
+	path := filepath.Join(t.TempDir(), "disk.img")
+	if err := os.WriteFile(path, image, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// openBlock opens the image with 64k of ram attached for dma
+func openBlock(t *testing.T, path string, mode BlockMode) (*BlockDevice, *memory.DataView) {
+	t.Helper()
+	ram := memory.CreateMemory(0x10000)
+	mapper := memorymapper.NewMemoryMapper()
+	mapper.Map(ram, 0, 0xffff)
+	b, err := OpenBlockDevice(path, mode, mapper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Close() })
+	return b, ram
+}
+
+func TestBlockCommands(t *testing.T) {
+	b, _ := openBlock(t, blockImage(t), BlockReadWrite)
+	if sectors := b.GetUint16(blockSectors); sectors != 3 {
+		t.Fatalf("SECTORS = %d, want 3", sectors)
+	}
+
+	tests := []struct {
+		sector  uint16
+		command uint16
+		status  uint16
+		window  [2]byte // bytes 0x00 and 0x80 of the window afterwards
+	}{
+		{1, BlockCmdRead, BlockStatusDone, [2]byte{0x01, 0x81}},
+		{2, BlockCmdRead, BlockStatusDone, [2]byte{0x02, 0x00}}, // the partial sector ends in zeros
+		{3, BlockCmdRead, BlockStatusDone | BlockStatusError, [2]byte{0x02, 0x00}},
+		{0, 9, BlockStatusDone | BlockStatusError, [2]byte{0x02, 0x00}},
+		{0, BlockCmdFlush, BlockStatusDone, [2]byte{0x02, 0x00}},
+	}
+	for _, test := range tests {
+		b.SetUint16(blockSector, test.sector)
+		b.SetUint16(blockCommand, test.command)
+		if status := b.GetUint16(blockStatus); status != test.status {
+			t.Errorf("command %d on sector %d: STATUS = %04X, want %04X", test.command, test.sector, status, test.status)
+		}
+		if window := [2]byte{b.GetUint8(blockWindow), b.GetUint8(blockWindow + 0x80)}; window != test.window {
+			t.Errorf("command %d on sector %d: window = % X, want % X", test.command, test.sector, window, test.window)
+		}
+	}
+}
+
+func TestBlockCommandByteWrite(t *testing.T) {
+	b, _ := openBlock(t, blockImage(t), BlockReadWrite)
+	b.SetUint16(blockSector, 1)
+
+	b.SetUint8(blockCommand, 0)
+	if status := b.GetUint16(blockStatus); status != 0 {
+		t.Fatalf("STATUS = %04X after the high byte of COMMAND, want 0 until the low byte is written", status)
+	}
+	b.SetUint8(blockCommand+1, BlockCmdRead)
+	if status, first := b.GetUint16(blockStatus), b.GetUint8(blockWindow); status != BlockStatusDone || first != 0x01 {
+		t.Errorf("STATUS = %04X, window[0] = %02X, want one read of sector 1", status, first)
+	}
+
+	b.SetUint8(blockSector, 0x00)
+	b.SetUint8(blockSector+1, 0x02)
+	if sector := b.GetUint16(blockSector); sector != 2 {
+		t.Errorf("SECTOR = %d after byte writes, want 2", sector)
+	}
+}
+
+func TestBlockDMA(t *testing.T) {
+	b, ram := openBlock(t, blockImage(t), BlockReadWrite)
+	b.SetUint16(blockSector, 1)
+	b.SetUint16(blockBuffer, 0x4000)
+	b.SetUint16(blockCommand, BlockCmdDMARead)
+	if got := ram.GetBuffer()[0x4000:0x4003]; !bytes.Equal(got, []byte{0x01, 0x02, 0x03}) {
+		t.Errorf("ram after the dma read = % X, want 01 02 03", got)
+	}
+
+	copy(ram.GetBuffer()[0x5000:], "sector zero")
+	b.SetUint16(blockSector, 0)
+	b.SetUint16(blockBuffer, 0x5000)
+	b.SetUint16(blockCommand, BlockCmdDMAWrite)
+	b.SetUint16(blockCommand, BlockCmdRead)
+	if got := b.window[:11]; string(got) != "sector zero" {
+		t.Errorf("sector 0 = %q after the dma write, want \"sector zero\"", got)
+	}
+
+	b.SetUint16(blockBuffer, 0xFF80) // the sector would run past the end of ram
+	b.SetUint16(blockCommand, BlockCmdDMARead)
+	if status := b.GetUint16(blockStatus); status != BlockStatusDone|BlockStatusError {
+		t.Errorf("STATUS = %04X for a transfer past the end of ram, want %04X", status, BlockStatusDone|BlockStatusError)
+	}
+}
+
+func TestBlockWriteModes(t *testing.T) {
+	tests := []struct {
+		mode     BlockMode
+		status   uint16
+		readBack byte // first byte of sector 1 read through the device
+		onDisk   byte // first byte of sector 1 in the image file
+	}{
+		{BlockReadWrite, BlockStatusDone, 0xAA, 0xAA},
+		{BlockReadOnly, BlockStatusDone | BlockStatusError | BlockStatusReadOnly, 0x01, 0x01},
+		{BlockCopyOnWrite, BlockStatusDone, 0xAA, 0x01},
+	}
+	for _, test := range tests {
+		path := blockImage(t)
+		b, _ := openBlock(t, path, test.mode)
+		b.SetUint16(blockSector, 1)
+		b.SetUint8(blockWindow, 0xAA)
+		b.SetUint16(blockCommand, BlockCmdWrite)
+		if status := b.GetUint16(blockStatus); status != test.status {
+			t.Errorf("mode %d: STATUS = %04X, want %04X", test.mode, status, test.status)
+		}
+
+		b.Reset()
+		b.SetUint16(blockSector, 1)
+		b.SetUint16(blockCommand, BlockCmdRead)
+		if got := b.GetUint8(blockWindow); got != test.readBack {
+			t.Errorf("mode %d: read back %02X, want %02X", test.mode, got, test.readBack)
+		}
+
+		image, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := image[BlockSectorSize]; got != test.onDisk {
+			t.Errorf("mode %d: image holds %02X, want %02X", test.mode, got, test.onDisk)
+		}
+	}
+}
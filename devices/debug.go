@@ -0,0 +1,93 @@
+package devices
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+//INFO: Debug port for printf style tracing from guest code. Output is collected into a line and written on FLUSH.
+//
+// Register layout (16 bit registers, offsets relative to the mapped start):
+//
+//	+0x00 CHAR   writing a byte (or the low byte of a word) appends the character
+//	+0x02 HEX    writing a word appends it as 0xXXXX
+//	+0x04 DEC    writing a word appends it in decimal
+//	+0x06 FLUSH  writing any value emits "[cycle N ip 0xXXXX] <line>" and starts a new line
+
+const (
+	DebugSize = 0x08
+
+	debugChar  = 0x00
+	debugHex   = 0x02
+	debugDec   = 0x04
+	debugFlush = 0x06
+)
+
+// CPUState is the part of the cpu the debug port reports
+type CPUState interface {
+	GetCycles() uint64
+	GetRegister(name string) uint16
+}
+
+// DebugDevice is a write only tracing port
+type DebugDevice struct {
+	w     io.Writer
+	cpu   CPUState
+	line  strings.Builder
+	latch registerLatch
+}
+
+// CreateDebugDevice writes the flushed lines to w (os.Stdout when nil), cpu is used for the line prefix and may be nil
+func CreateDebugDevice(w io.Writer, cpu CPUState) *DebugDevice {
+	if w == nil {
+		w = os.Stdout
+	}
+	return &DebugDevice{w: w, cpu: cpu}
+}
+
+func (d *DebugDevice) GetUint16(address int) uint16 {
+	return 0
+}
+
+func (d *DebugDevice) GetUint8(address int) uint8 {
+	return 0
+}
+
+func (d *DebugDevice) SetUint16(address int, value uint16) {
+	switch address &^ 1 {
+	case debugChar:
+		d.line.WriteByte(byte(value))
+	case debugHex:
+		fmt.Fprintf(&d.line, "0x%04X", value)
+	case debugDec:
+		fmt.Fprintf(&d.line, "%d", value)
+	case debugFlush:
+		d.Flush()
+	}
+}
+
+func (d *DebugDevice) SetUint8(address int, value uint8) {
+	if address&^1 == debugChar {
+		d.line.WriteByte(value)
+		return
+	}
+	d.latch.writeByte(d, address, value) // HEX and DEC print the whole word, FLUSH emits one line
+}
+
+// Reset drops the unflushed line
+func (d *DebugDevice) Reset() {
+	d.line.Reset()
+	d.latch = registerLatch{}
+}
+
+// Flush writes the pending line, prefixed with the cycle count and ip when a cpu is attached
+func (d *DebugDevice) Flush() {
+	if d.cpu != nil {
+		fmt.Fprintf(d.w, "[cycle %d ip 0x%04X] %s\n", d.cpu.GetCycles(), d.cpu.GetRegister("ip"), d.line.String())
+	} else {
+		fmt.Fprintf(d.w, "%s\n", d.line.String())
+	}
+	d.line.Reset()
+}
@@ -0,0 +1,83 @@
+package devices
+
+import (
+	"strings"
+	"testing"
+)
+
+// testCPUState is a cpu at a fixed cycle and ip
+type testCPUState struct {
+	cycles uint64
+	ip     uint16
+}
+
+func (c testCPUState) GetCycles() uint64 { return c.cycles }
+
+func (c testCPUState) GetRegister(name string) uint16 {
+	if name == "ip" {
+		return c.ip
+	}
+	return 0
+}
+
+func TestDebugOutput(t *testing.T) {
+	type write struct {
+		address int
+		value   uint16
+		bytes   bool // written as two byte accesses, high byte first
+	}
+	tests := []struct {
+		name   string
+		writes []write
+		want   string
+	}{
+		{"chars", []write{{debugChar, 'o', false}, {debugChar + 1, 'k', false}}, "ok\n"},
+		{"char word", []write{{debugChar, 0x4142, false}}, "B\n"},
+		{"char bytes", []write{{debugChar, 0x4142, true}}, "AB\n"},
+		{"hex", []write{{debugHex, 0xBEEF, false}}, "0xBEEF\n"},
+		{"hex bytes", []write{{debugHex, 0xBEEF, true}}, "0xBEEF\n"},
+		{"hex low byte", []write{{debugHex + 1, 0x7F, false}}, "0x007F\n"},
+		{"dec", []write{{debugDec, 65535, false}}, "65535\n"},
+		{"dec bytes", []write{{debugDec, 1234, true}}, "1234\n"},
+		{"mixed", []write{{debugChar, 'x', false}, {debugChar, '=', false}, {debugDec, 7, false}}, "x=7\n"},
+	}
+	for _, test := range tests {
+		var out strings.Builder
+		d := CreateDebugDevice(&out, nil)
+		for _, w := range test.writes {
+			if w.bytes {
+				d.SetUint8(w.address&^1, uint8(w.value>>8))
+				d.SetUint8(w.address|1, uint8(w.value))
+			} else if w.address%2 == 1 {
+				d.SetUint8(w.address, uint8(w.value))
+			} else {
+				d.SetUint16(w.address, w.value)
+			}
+		}
+		d.SetUint8(debugFlush, 0)
+		d.SetUint8(debugFlush+1, 0)
+		if got := out.String(); got != test.want {
+			t.Errorf("%s: output %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestDebugFlush(t *testing.T) {
+	var out strings.Builder
+	d := CreateDebugDevice(&out, testCPUState{cycles: 42, ip: 0x0123})
+	d.SetUint16(debugChar, 'a')
+	d.SetUint16(debugFlush, 0)
+	d.SetUint16(debugFlush, 0)
+	d.SetUint16(debugChar, 'b')
+	d.Reset()
+	d.SetUint16(debugChar, 'c')
+	d.Flush()
+
+	want := "[cycle 42 ip 0x0123] a\n[cycle 42 ip 0x0123] \n[cycle 42 ip 0x0123] c\n"
+	if got := out.String(); got != want {
+		t.Errorf("output %q, want %q", got, want)
+	}
+	if d.GetUint16(debugHex) != 0 || d.GetUint8(debugChar) != 0 {
+		t.Error("the write only port reads back data")
+	}
+}
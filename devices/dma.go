@@ -0,0 +1,177 @@
+package devices
+
+import (
+	memorymapper "github.com/martbul/memoryMapper"
+)
+
+//INFO: DMA controller. Copies memory (or device registers) through the memory mapper without the cpu executing a MOV per word.
+// The transfer runs in the background, a fixed number of units is moved for every cpu cycle.
+//
+// Register layout (16 bit registers, offsets relative to the mapped start):
+//
+//	+0x00 SRC        source address, advances by SRC_STRIDE after every unit
+//	+0x02 DST        destination address, advances by DST_STRIDE after every unit
+//	+0x04 LENGTH     number of units left to copy
+//	+0x06 SRC_STRIDE added to SRC after every unit (0 keeps reading the same address)
+//	+0x08 DST_STRIDE added to DST after every unit (0 keeps writing the same address, like a device port)
+//	+0x0A CTRL       bit0 start, bit1 word width (0 = byte), bit2 interupt enable
+//	+0x0C STATUS     bit0 busy, bit1 done, bit2 error. Writing a 1 clears done/error
+
+const (
+	DMACtrlStart     = 1 << 0
+	DMACtrlWord      = 1 << 1
+	DMACtrlInterrupt = 1 << 2
+
+	DMAStatusBusy  = 1 << 0
+	DMAStatusDone  = 1 << 1
+	DMAStatusError = 1 << 2
+
+	DMASize = 0x0E
+
+	dmaSrc       = 0x00
+	dmaDst       = 0x02
+	dmaLength    = 0x04
+	dmaSrcStride = 0x06
+	dmaDstStride = 0x08
+	dmaCtrl      = 0x0A
+	dmaStatus    = 0x0C
+)
+
+// DMADevice is a single channel dma controller
+type DMADevice struct {
+	memory        *memorymapper.MemoryMapper
+	unitsPerCycle int
+	interupt      func(uint16)
+	interuptValue uint16
+
+	src       uint16
+	dst       uint16
+	length    uint16
+	srcStride uint16
+	dstStride uint16
+	ctrl      uint16
+	status    uint16
+	latch     registerLatch
+}
+
+// CreateDMADevice creates a controller moving unitsPerCycle bytes/words per cpu cycle.
+// interupt is called with interuptValue when a transfer finishes, usually cpu.RequestInterupt. It may be nil
+func CreateDMADevice(memory *memorymapper.MemoryMapper, unitsPerCycle int, interupt func(uint16), interuptValue uint16) *DMADevice {
+	if unitsPerCycle < 1 {
+		unitsPerCycle = 1
+	}
+	return &DMADevice{
+		memory:        memory,
+		unitsPerCycle: unitsPerCycle,
+		interupt:      interupt,
+		interuptValue: interuptValue,
+	}
+}
+
+func (d *DMADevice) GetUint16(address int) uint16 {
+	switch address &^ 1 {
+	case dmaSrc:
+		return d.src
+	case dmaDst:
+		return d.dst
+	case dmaLength:
+		return d.length
+	case dmaSrcStride:
+		return d.srcStride
+	case dmaDstStride:
+		return d.dstStride
+	case dmaCtrl:
+		return d.ctrl
+	case dmaStatus:
+		return d.status
+	}
+	return 0
+}
+
+func (d *DMADevice) SetUint16(address int, value uint16) {
+	switch address &^ 1 {
+	case dmaSrc:
+		d.src = value
+	case dmaDst:
+		d.dst = value
+	case dmaLength:
+		d.length = value
+	case dmaSrcStride:
+		d.srcStride = value
+	case dmaDstStride:
+		d.dstStride = value
+	case dmaCtrl:
+		d.ctrl = value
+		if value&DMACtrlStart != 0 {
+			d.status = DMAStatusBusy
+		}
+	case dmaStatus:
+		d.status &^= value & (DMAStatusDone | DMAStatusError)
+	}
+}
+
+func (d *DMADevice) GetUint8(address int) uint8 {
+	return registerByte(d, address)
+}
+
+func (d *DMADevice) SetUint8(address int, value uint8) {
+	switch address &^ 1 {
+	case dmaCtrl, dmaStatus:
+		d.latch.writeByte(d, address, value) // CTRL starts a transfer, STATUS is write 1 to clear
+	default:
+		setRegisterByte(d, address, value)
+	}
+}
+
+// Reset aborts a running transfer and clears the registers
+func (d *DMADevice) Reset() {
+	d.src, d.dst, d.length = 0, 0, 0
+	d.srcStride, d.dstStride = 0, 0
+	d.ctrl, d.status = 0, 0
+	d.latch = registerLatch{}
+}
+
+// Tick moves the next units of a running transfer
+func (d *DMADevice) Tick(cycles int) {
+	if d.status&DMAStatusBusy == 0 {
+		return
+	}
+
+	for i := 0; i < cycles*d.unitsPerCycle && d.length > 0; i++ {
+		if err := d.transferUnit(); err != nil {
+			d.finish(DMAStatusError)
+			return
+		}
+		d.src += d.srcStride
+		d.dst += d.dstStride
+		d.length--
+	}
+
+	if d.length == 0 {
+		d.finish(0)
+	}
+}
+
+func (d *DMADevice) transferUnit() error {
+	if d.ctrl&DMACtrlWord != 0 {
+		value, err := d.memory.GetUint16(int(d.src))
+		if err != nil {
+			return err
+		}
+		return d.memory.SetUint16(int(d.dst), value)
+	}
+
+	value, err := d.memory.GetUint8(int(d.src))
+	if err != nil {
+		return err
+	}
+	return d.memory.SetUint8(int(d.dst), value)
+}
+
+func (d *DMADevice) finish(status uint16) {
+	d.ctrl &^= DMACtrlStart
+	d.status = DMAStatusDone | status
+	if d.ctrl&DMACtrlInterrupt != 0 && d.interupt != nil {
+		d.interupt(d.interuptValue)
+	}
+}
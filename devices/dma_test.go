@@ -0,0 +1,116 @@
+package devices
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/martbul/memory"
+	memorymapper "github.com/martbul/memoryMapper"
+)
+
+// newTestDMA creates a controller moving two units per cycle over 4k of ram,
+// the rest of the address space is unmapped
+func newTestDMA(interupt func(uint16)) (*DMADevice, []byte) {
+	ram := memory.CreateMemory(0x1000)
+	mapper := memorymapper.NewMemoryMapper()
+	mapper.Map(ram, 0, 0x0fff)
+	return CreateDMADevice(mapper, 2, interupt, 0x20), ram.GetBuffer()
+}
+
+func TestDMATransfers(t *testing.T) {
+	tests := []struct {
+		name                 string
+		src, dst, length     uint16
+		srcStride, dstStride uint16
+		ctrl                 uint16
+		want                 []byte // ram from 0x100 afterwards
+		status               uint16
+	}{
+		{"bytes", 0x000, 0x100, 4, 1, 1, 0, []byte{0x00, 0x01, 0x02, 0x03, 0x00}, DMAStatusDone},
+		{"source stride", 0x000, 0x100, 3, 2, 1, 0, []byte{0x00, 0x02, 0x04, 0x00}, DMAStatusDone},
+		{"port", 0x000, 0x100, 4, 1, 0, 0, []byte{0x03, 0x00}, DMAStatusDone},
+		{"fill", 0x005, 0x100, 3, 0, 1, 0, []byte{0x05, 0x05, 0x05, 0x00}, DMAStatusDone},
+		{"words", 0x000, 0x100, 2, 2, 2, DMACtrlWord, []byte{0x00, 0x01, 0x02, 0x03, 0x00}, DMAStatusDone},
+		{"unmapped source", 0xfff, 0x100, 2, 1, 1, 0, []byte{0xff, 0x00}, DMAStatusDone | DMAStatusError},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var raised []uint16
+			d, ram := newTestDMA(func(n uint16) { raised = append(raised, n) })
+			for i := range 0x100 {
+				ram[i] = byte(i)
+			}
+			ram[0xfff] = 0xff
+			d.SetUint16(dmaSrc, test.src)
+			d.SetUint16(dmaDst, test.dst)
+			d.SetUint16(dmaLength, test.length)
+			d.SetUint16(dmaSrcStride, test.srcStride)
+			d.SetUint16(dmaDstStride, test.dstStride)
+			d.SetUint16(dmaCtrl, test.ctrl|DMACtrlStart|DMACtrlInterrupt)
+			d.Tick(10)
+
+			if got := ram[0x100 : 0x100+len(test.want)]; !bytes.Equal(got, test.want) {
+				t.Errorf("ram = % X, want % X", got, test.want)
+			}
+			if status := d.GetUint16(dmaStatus); status != test.status {
+				t.Errorf("STATUS = %04X, want %04X", status, test.status)
+			}
+			if len(raised) != 1 || raised[0] != 0x20 {
+				t.Errorf("interrupts = %v, want one 0x20", raised)
+			}
+		})
+	}
+}
+
+func TestDMAPacing(t *testing.T) {
+	var raised int
+	d, ram := newTestDMA(func(uint16) { raised++ })
+	d.SetUint16(dmaDst, 0x100)
+	d.SetUint16(dmaLength, 5)
+	d.SetUint16(dmaSrcStride, 1)
+	d.SetUint16(dmaDstStride, 1)
+	ram[4] = 0x44
+
+	d.SetUint16(dmaCtrl, DMACtrlStart)
+	d.Tick(2)
+	if length, status := d.GetUint16(dmaLength), d.GetUint16(dmaStatus); length != 1 || status != DMAStatusBusy {
+		t.Errorf("after 2 cycles LENGTH = %d, STATUS = %04X, want 1 unit left and busy", length, status)
+	}
+	d.Tick(1)
+	if status := d.GetUint16(dmaStatus); status != DMAStatusDone || ram[0x104] != 0x44 {
+		t.Errorf("after 3 cycles STATUS = %04X, ram[0x104] = %02X, want done and 44", status, ram[0x104])
+	}
+	if ctrl := d.GetUint16(dmaCtrl); ctrl&DMACtrlStart != 0 || raised != 0 {
+		t.Errorf("CTRL = %04X with %d interrupts, want start cleared and no interrupt", ctrl, raised)
+	}
+}
+
+func TestDMAByteWrites(t *testing.T) {
+	d, ram := newTestDMA(nil)
+	d.SetUint8(dmaDst, 0x01)
+	d.SetUint8(dmaDst+1, 0x00)
+	d.SetUint8(dmaLength+1, 1)
+	ram[0] = 0x99
+
+	// the transfer starts once, when the low byte of CTRL is written
+	d.SetUint8(dmaCtrl, 0)
+	if status := d.GetUint16(dmaStatus); status != 0 {
+		t.Fatalf("STATUS = %04X after the high byte of CTRL, want 0", status)
+	}
+	d.SetUint8(dmaCtrl+1, DMACtrlStart)
+	d.Tick(1)
+	if ram[0x100] != 0x99 || d.GetUint16(dmaStatus) != DMAStatusDone {
+		t.Fatalf("ram[0x100] = %02X, STATUS = %04X, want 99 and done", ram[0x100], d.GetUint16(dmaStatus))
+	}
+
+	// writing 0 to the high byte of STATUS must not clear done
+	d.SetUint8(dmaStatus, 0)
+	d.SetUint8(dmaStatus+1, 0)
+	if status := d.GetUint16(dmaStatus); status != DMAStatusDone {
+		t.Errorf("STATUS = %04X after writing zeros, want done", status)
+	}
+	d.SetUint8(dmaStatus+1, DMAStatusDone)
+	if status := d.GetUint16(dmaStatus); status != 0 {
+		t.Errorf("STATUS = %04X after clearing done, want 0", status)
+	}
+}
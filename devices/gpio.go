@@ -0,0 +1,190 @@
+package devices
+
+import "fmt"
+
+//INFO: GPIO port with 16 pins. Go code drives the input pins (buttons) and observes the output pins (leds).
+// Every output change is reported with the cpu cycle it happened in.
+//
+// Register layout (16 bit registers, offsets relative to the mapped start), bit n is pin n:
+//
+//	+0x00 DIR      1 = output, 0 = input
+//	+0x02 OUT      output latch, only pins configured as outputs are driven
+//	+0x04 IN       current pin levels (outputs read back the latch)
+//	+0x06 RISE_IE  interupt on a rising edge of an input pin
+//	+0x08 FALL_IE  interupt on a falling edge of an input pin
+//	+0x0A EDGE     pins that saw an enabled edge, writing a 1 clears the bit
+
+const (
+	GPIOPins = 16
+	GPIOSize = 0x0C
+
+	gpioDir    = 0x00
+	gpioOut    = 0x02
+	gpioIn     = 0x04
+	gpioRiseIE = 0x06
+	gpioFallIE = 0x08
+	gpioEdge   = 0x0A
+)
+
+// GPIOChange describes an output pin changing its level
+type GPIOChange struct {
+	Pin   int
+	Level bool
+	Cycle uint64
+}
+
+// GPIODevice is a 16 pin general purpose io port
+type GPIODevice struct {
+	dir    uint16
+	out    uint16
+	input  uint16 // levels driven from go
+	riseIE uint16
+	fallIE uint16
+	edge   uint16
+
+	clock         func() uint64
+	interupt      func(uint16)
+	interuptValue uint16
+	listeners     []func(GPIOChange)
+	latch         registerLatch
+}
+
+// CreateGPIODevice creates the port, clock stamps the output changes (usually cpu.GetCycles) and may be nil.
+// interupt is called with interuptValue on an enabled edge, usually cpu.RequestInterupt. It may be nil
+func CreateGPIODevice(clock func() uint64, interupt func(uint16), interuptValue uint16) *GPIODevice {
+	return &GPIODevice{
+		clock:         clock,
+		interupt:      interupt,
+		interuptValue: interuptValue,
+	}
+}
+
+// OnOutputChange subscribes to level changes of the output pins
+func (g *GPIODevice) OnOutputChange(listener func(GPIOChange)) {
+	g.listeners = append(g.listeners, listener)
+}
+
+// SetInput drives an input pin from go, edges are detected against the previous level.
+// It fails for a pin outside of 0 - 15
+func (g *GPIODevice) SetInput(pin int, level bool) error {
+	if pin < 0 || pin >= GPIOPins {
+		return fmt.Errorf("gpio: pin %d outside of 0 - %d", pin, GPIOPins-1)
+	}
+	previous := g.pins()
+	if level {
+		g.input |= 1 << pin
+	} else {
+		g.input &^= 1 << pin
+	}
+	g.detectEdges(previous, g.pins())
+	return nil
+}
+
+// Output returns the level of a pin as seen from outside (the latch for outputs, the driven level for inputs).
+// A pin outside of 0 - 15 reads as low
+func (g *GPIODevice) Output(pin int) bool {
+	if pin < 0 || pin >= GPIOPins {
+		return false
+	}
+	return g.pins()&(1<<pin) != 0
+}
+
+// Reset turns every pin into an input, listeners see the outputs drop
+func (g *GPIODevice) Reset() {
+	previous := g.pins()
+	outputs := g.dir
+	g.dir, g.out = 0, 0
+	g.riseIE, g.fallIE, g.edge = 0, 0, 0
+	g.latch = registerLatch{}
+	g.notify(previous&outputs, 0, outputs)
+}
+
+func (g *GPIODevice) GetUint16(address int) uint16 {
+	switch address &^ 1 {
+	case gpioDir:
+		return g.dir
+	case gpioOut:
+		return g.out
+	case gpioIn:
+		return g.pins()
+	case gpioRiseIE:
+		return g.riseIE
+	case gpioFallIE:
+		return g.fallIE
+	case gpioEdge:
+		return g.edge
+	}
+	return 0
+}
+
+func (g *GPIODevice) SetUint16(address int, value uint16) {
+	switch address &^ 1 {
+	case gpioDir:
+		previous := g.pins()
+		g.dir = value
+		g.notify(previous, g.pins(), g.dir)
+	case gpioOut:
+		previous := g.pins()
+		g.out = value
+		g.notify(previous, g.pins(), g.dir)
+	case gpioRiseIE:
+		g.riseIE = value
+	case gpioFallIE:
+		g.fallIE = value
+	case gpioEdge:
+		g.edge &^= value
+	}
+}
+
+func (g *GPIODevice) GetUint8(address int) uint8 {
+	return registerByte(g, address)
+}
+
+func (g *GPIODevice) SetUint8(address int, value uint8) {
+	if address&^1 == gpioEdge {
+		g.latch.writeByte(g, address, value) // write 1 to clear, writing back what was read would clear every bit
+		return
+	}
+	setRegisterByte(g, address, value)
+}
+
+// pins returns the level of every pin
+func (g *GPIODevice) pins() uint16 {
+	return (g.out & g.dir) | (g.input &^ g.dir)
+}
+
+// notify reports the output pins that changed level to the listeners
+func (g *GPIODevice) notify(previous, current, outputs uint16) {
+	changed := (previous ^ current) & outputs
+	if changed == 0 || len(g.listeners) == 0 {
+		return
+	}
+
+	var cycle uint64
+	if g.clock != nil {
+		cycle = g.clock()
+	}
+	for pin := 0; pin < GPIOPins; pin++ {
+		if changed&(1<<pin) == 0 {
+			continue
+		}
+		change := GPIOChange{Pin: pin, Level: current&(1<<pin) != 0, Cycle: cycle}
+		for _, listener := range g.listeners {
+			listener(change)
+		}
+	}
+}
+
+func (g *GPIODevice) detectEdges(previous, current uint16) {
+	inputs := ^g.dir
+	rising := ^previous & current & inputs & g.riseIE
+	falling := previous & ^current & inputs & g.fallIE
+	if rising|falling == 0 {
+		return
+	}
+
+	g.edge |= rising | falling
+	if g.interupt != nil {
+		g.interupt(g.interuptValue)
+	}
+}
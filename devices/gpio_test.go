@@ -0,0 +1,96 @@
+package devices
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGPIOPinRange(t *testing.T) {
+	g := CreateGPIODevice(nil, nil, 0)
+	for _, pin := range []int{-1, 16, 100} {
+		if err := g.SetInput(pin, true); err == nil {
+			t.Errorf("SetInput(%d) did not fail", pin)
+		}
+		if g.Output(pin) {
+			t.Errorf("Output(%d) is high", pin)
+		}
+	}
+	if g.GetUint16(gpioIn) != 0 {
+		t.Error("an out of range pin changed the levels")
+	}
+	if err := g.SetInput(15, true); err != nil || !g.Output(15) {
+		t.Errorf("pin 15: err %v, level %v", err, g.Output(15))
+	}
+}
+
+func TestGPIOOutputs(t *testing.T) {
+	var cycle uint64 = 10
+	g := CreateGPIODevice(func() uint64 { return cycle }, nil, 0)
+	var changes []GPIOChange
+	g.OnOutputChange(func(change GPIOChange) { changes = append(changes, change) })
+
+	g.SetInput(1, true)
+	g.SetUint16(gpioDir, 0x0003)
+	g.SetUint16(gpioOut, 0x0001)
+	cycle = 20
+	g.SetUint16(gpioOut, 0x0000)
+
+	want := []GPIOChange{{Pin: 1, Level: false, Cycle: 10}, {Pin: 0, Level: true, Cycle: 10}, {Pin: 0, Level: false, Cycle: 20}}
+	if !slices.Equal(changes, want) {
+		t.Errorf("changes = %v, want %v", changes, want)
+	}
+	if in := g.GetUint16(gpioIn); in != 0 {
+		t.Errorf("IN = %04X, want the output latch 0000", in)
+	}
+}
+
+func TestGPIOEdges(t *testing.T) {
+	var raised int
+	g := CreateGPIODevice(nil, func(uint16) { raised++ }, 0)
+	g.SetUint16(gpioRiseIE, 0x0101)
+	g.SetUint16(gpioFallIE, 0x0100)
+
+	g.SetInput(0, true)
+	g.SetInput(8, true)
+	g.SetInput(8, false)
+	g.SetInput(0, false) // falling edge of pin 0 is not enabled
+	if edge := g.GetUint16(gpioEdge); edge != 0x0101 || raised != 3 {
+		t.Fatalf("EDGE = %04X with %d interrupts, want 0101 with 3", edge, raised)
+	}
+
+	// clearing the low byte must leave the high byte alone
+	g.SetUint8(gpioEdge+1, 0x01)
+	if edge := g.GetUint16(gpioEdge); edge != 0x0100 {
+		t.Errorf("EDGE after clearing bit 0 with a byte write = %04X, want 0100", edge)
+	}
+	g.SetUint8(gpioEdge, 0x01)
+	g.SetUint8(gpioEdge+1, 0x00)
+	if edge := g.GetUint16(gpioEdge); edge != 0 {
+		t.Errorf("EDGE after clearing bit 8 with byte writes = %04X, want 0", edge)
+	}
+}
+
+func TestGPIOByteAccess(t *testing.T) {
+	g := CreateGPIODevice(nil, nil, 0)
+	g.SetUint16(gpioDir, 0xFFFF)
+	g.SetUint8(gpioOut, 0xAB)
+	g.SetUint8(gpioOut+1, 0xCD)
+	if out := g.GetUint16(gpioOut); out != 0xABCD {
+		t.Errorf("OUT = %04X, want ABCD", out)
+	}
+	if high, low := g.GetUint8(gpioIn), g.GetUint8(gpioIn+1); high != 0xAB || low != 0xCD {
+		t.Errorf("IN bytes = %02X %02X, want AB CD", high, low)
+	}
+}
+
+func TestGPIOReset(t *testing.T) {
+	g := CreateGPIODevice(nil, nil, 0)
+	var changes []GPIOChange
+	g.OnOutputChange(func(change GPIOChange) { changes = append(changes, change) })
+	g.SetUint16(gpioDir, 0x0004)
+	g.SetUint16(gpioOut, 0x0004)
+	g.Reset()
+	if len(changes) != 2 || changes[1].Level || g.GetUint16(gpioDir) != 0 {
+		t.Errorf("changes = %v, DIR = %04X, want the output to drop and every pin an input", changes, g.GetUint16(gpioDir))
+	}
+}
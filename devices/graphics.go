@@ -0,0 +1,229 @@
+package devices
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"os"
+)
+
+//INFO: Bitmapped graphics device. 128x128 pixels, 4 bits per pixel (2 pixels per byte, left pixel in the high nibble)
+// indexing a 16 color palette. A vblank happens every cyclesPerFrame cpu cycles.
+//
+// Memory layout (offsets relative to the mapped start):
+//
+//	0x0000 - 0x1FFF framebuffer, row by row (64 bytes per row)
+//	0x2000 - 0x201F palette, 16 registers of 16 bits: 0x0RGB (4 bits per component)
+//	0x2020          CTRL   bit0 vblank interupt enable
+//	0x2022          STATUS bit0 vblank happened, writing a 1 clears it
+
+const (
+	GraphicsWidth  = 128
+	GraphicsHeight = 128
+	GraphicsColors = 16
+	GraphicsSize   = 0x2024
+
+	GraphicsCtrlVblankInterrupt = 1 << 0
+	GraphicsStatusVblank        = 1 << 0
+
+	graphicsFramebufferSize = GraphicsWidth * GraphicsHeight / 2
+	graphicsPalette         = 0x2000
+	graphicsCtrl            = 0x2020
+	graphicsStatus          = 0x2022
+)
+
+// GraphicsDevice is a pixel framebuffer with a palette
+type GraphicsDevice struct {
+	framebuffer    []byte
+	palette        [GraphicsColors]uint16
+	ctrl           uint16
+	status         uint16
+	cyclesPerFrame int
+	frameCycles    int
+	interupt       func(uint16)
+	interuptValue  uint16
+	latch          registerLatch
+
+	recording bool
+	frames    []*image.Paletted
+}
+
+// CreateGraphicsDevice creates the framebuffer with a vblank every cyclesPerFrame cycles.
+// interupt is called with interuptValue on vblank when enabled in CTRL, usually cpu.RequestInterupt. It may be nil
+func CreateGraphicsDevice(cyclesPerFrame int, interupt func(uint16), interuptValue uint16) *GraphicsDevice {
+	g := &GraphicsDevice{
+		framebuffer:    make([]byte, graphicsFramebufferSize),
+		cyclesPerFrame: cyclesPerFrame,
+		interupt:       interupt,
+		interuptValue:  interuptValue,
+	}
+	// default palette: a grey ramp from black to white
+	for i := range g.palette {
+		g.palette[i] = uint16(i)<<8 | uint16(i)<<4 | uint16(i)
+	}
+	return g
+}
+
+func (g *GraphicsDevice) GetUint8(address int) uint8 {
+	if address < graphicsFramebufferSize {
+		return g.framebuffer[address]
+	}
+	return registerByte(g, address)
+}
+
+func (g *GraphicsDevice) SetUint8(address int, value uint8) {
+	if address < graphicsFramebufferSize {
+		g.framebuffer[address] = value
+		return
+	}
+	if address&^1 == graphicsStatus {
+		g.latch.writeByte(g, address, value) // a vblank bit written back would be cleared
+		return
+	}
+	setRegisterByte(g, address, value)
+}
+
+func (g *GraphicsDevice) GetUint16(address int) uint16 {
+	if address < graphicsFramebufferSize {
+		return uint16(g.framebuffer[address])<<8 | uint16(g.GetUint8(address+1))
+	}
+
+	address &^= 1
+	switch {
+	case address >= graphicsPalette && address < graphicsCtrl:
+		return g.palette[(address-graphicsPalette)/2]
+	case address == graphicsCtrl:
+		return g.ctrl
+	case address == graphicsStatus:
+		return g.status
+	}
+	return 0
+}
+
+func (g *GraphicsDevice) SetUint16(address int, value uint16) {
+	if address < graphicsFramebufferSize {
+		g.framebuffer[address] = uint8(value >> 8)
+		g.SetUint8(address+1, uint8(value))
+		return
+	}
+
+	address &^= 1
+	switch {
+	case address >= graphicsPalette && address < graphicsCtrl:
+		g.palette[(address-graphicsPalette)/2] = value & 0x0FFF
+	case address == graphicsCtrl:
+		g.ctrl = value
+	case address == graphicsStatus:
+		g.status &^= value
+	}
+}
+
+// Reset restores the default palette and clears the registers, the framebuffer keeps its content
+func (g *GraphicsDevice) Reset() {
+	for i := range g.palette {
+		g.palette[i] = uint16(i)<<8 | uint16(i)<<4 | uint16(i)
+	}
+	g.ctrl = 0
+	g.status = 0
+	g.frameCycles = 0
+	g.latch = registerLatch{}
+}
+
+// Tick counts cycles towards the next vblank
+func (g *GraphicsDevice) Tick(cycles int) {
+	if g.cyclesPerFrame <= 0 {
+		return
+	}
+
+	g.frameCycles += cycles
+	for g.frameCycles >= g.cyclesPerFrame {
+		g.frameCycles -= g.cyclesPerFrame
+		g.vblank()
+	}
+}
+
+func (g *GraphicsDevice) vblank() {
+	g.status |= GraphicsStatusVblank
+	if g.recording {
+		g.frames = append(g.frames, g.Image())
+	}
+	if g.ctrl&GraphicsCtrlVblankInterrupt != 0 && g.interupt != nil {
+		g.interupt(g.interuptValue)
+	}
+}
+
+// Pixel returns the palette index of the pixel at x, y
+func (g *GraphicsDevice) Pixel(x, y int) uint8 {
+	value := g.framebuffer[(y*GraphicsWidth+x)/2]
+	if x%2 == 0 {
+		return value >> 4
+	}
+	return value & 0x0F
+}
+
+// Image returns a snapshot of the current frame
+func (g *GraphicsDevice) Image() *image.Paletted {
+	palette := make(color.Palette, GraphicsColors)
+	for i, rgb := range g.palette {
+		// scale every 4 bit component to 8 bits (0xF -> 0xFF)
+		palette[i] = color.RGBA{
+			R: uint8(rgb>>8&0xF) * 0x11,
+			G: uint8(rgb>>4&0xF) * 0x11,
+			B: uint8(rgb&0xF) * 0x11,
+			A: 0xFF,
+		}
+	}
+
+	img := image.NewPaletted(image.Rect(0, 0, GraphicsWidth, GraphicsHeight), palette)
+	for y := 0; y < GraphicsHeight; y++ {
+		for x := 0; x < GraphicsWidth; x++ {
+			img.SetColorIndex(x, y, g.Pixel(x, y))
+		}
+	}
+	return img
+}
+
+// SavePNG writes the current frame as a png file
+func (g *GraphicsDevice) SavePNG(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, g.Image()); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// StartRecording captures a frame on every vblank until SaveGIF is called
+func (g *GraphicsDevice) StartRecording() {
+	g.recording = true
+	g.frames = nil
+}
+
+// Frames returns the frames captured since StartRecording
+func (g *GraphicsDevice) Frames() []*image.Paletted {
+	return g.frames
+}
+
+// SaveGIF writes the recorded frames as an animated gif, delay is in 100ths of a second per frame
+func (g *GraphicsDevice) SaveGIF(path string, delay int) error {
+	animation := &gif.GIF{}
+	for _, frame := range g.frames {
+		animation.Image = append(animation.Image, frame)
+		animation.Delay = append(animation.Delay, delay)
+	}
+	g.recording = false
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := gif.EncodeAll(file, animation); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
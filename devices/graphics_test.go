@@ -0,0 +1,101 @@
+package devices
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestGraphicsRegisters(t *testing.T) {
+	tests := []struct {
+		name    string
+		address int
+		write   uint16
+		want    uint16
+	}{
+		{"framebuffer word", 0x0040, 0x12AB, 0x12AB},
+		{"last framebuffer word", graphicsFramebufferSize - 2, 0x3456, 0x3456},
+		{"palette keeps 12 bits", graphicsPalette + 2, 0xFABC, 0x0ABC},
+		{"last palette entry", graphicsPalette + 0x1E, 0x0F00, 0x0F00},
+		{"ctrl", graphicsCtrl, GraphicsCtrlVblankInterrupt, GraphicsCtrlVblankInterrupt},
+	}
+	for _, test := range tests {
+		g := CreateGraphicsDevice(0, nil, 0)
+		g.SetUint16(test.address, test.write)
+		if got := g.GetUint16(test.address); got != test.want {
+			t.Errorf("%s: read %04X, want %04X", test.name, got, test.want)
+		}
+		if high, low := g.GetUint8(test.address), g.GetUint8(test.address+1); uint16(high)<<8|uint16(low) != test.want {
+			t.Errorf("%s: bytes %02X %02X, want %04X", test.name, high, low, test.want)
+		}
+	}
+}
+
+func TestGraphicsPixels(t *testing.T) {
+	g := CreateGraphicsDevice(0, nil, 0)
+	g.SetUint8(0, 0x1F)
+	g.SetUint8(GraphicsWidth/2*3+1, 0xA0) // x 2 and 3 of row 3
+	g.SetUint16(graphicsPalette+0xF*2, 0x0F80)
+
+	for _, pixel := range []struct{ x, y, index int }{{0, 0, 1}, {1, 0, 15}, {2, 0, 0}, {2, 3, 10}, {3, 3, 0}} {
+		if got := g.Pixel(pixel.x, pixel.y); int(got) != pixel.index {
+			t.Errorf("Pixel(%d, %d) = %d, want %d", pixel.x, pixel.y, got, pixel.index)
+		}
+	}
+	img := g.Image()
+	if got, want := img.At(1, 0), (color.RGBA{0xFF, 0x88, 0x00, 0xFF}); got != want {
+		t.Errorf("pixel 1, 0 = %v, want %v", got, want)
+	}
+	if got, want := img.At(2, 3), (color.RGBA{0xAA, 0xAA, 0xAA, 0xFF}); got != want {
+		t.Errorf("pixel 2, 3 = %v, want the default grey %v", got, want)
+	}
+}
+
+func TestGraphicsVblank(t *testing.T) {
+	var raised []uint16
+	g := CreateGraphicsDevice(100, func(n uint16) { raised = append(raised, n) }, 0x30)
+	g.Tick(99)
+	if status := g.GetUint16(graphicsStatus); status != 0 {
+		t.Fatalf("STATUS = %04X before the first frame, want 0", status)
+	}
+	g.Tick(1)
+	if status := g.GetUint16(graphicsStatus); status != GraphicsStatusVblank || len(raised) != 0 {
+		t.Fatalf("STATUS = %04X with %d interrupts, want a vblank without interrupt", status, len(raised))
+	}
+
+	g.SetUint16(graphicsCtrl, GraphicsCtrlVblankInterrupt)
+	g.Tick(250)
+	if len(raised) != 2 || raised[0] != 0x30 {
+		t.Errorf("interrupts = %v, want two 0x30", raised)
+	}
+
+	// writing 0 must leave the bit set, a byte write must not write back what it read
+	g.SetUint8(graphicsStatus, 0)
+	g.SetUint8(graphicsStatus+1, 0)
+	if status := g.GetUint16(graphicsStatus); status != GraphicsStatusVblank {
+		t.Errorf("STATUS = %04X after writing zeros, want the vblank bit", status)
+	}
+	g.SetUint8(graphicsStatus+1, GraphicsStatusVblank)
+	if status := g.GetUint16(graphicsStatus); status != 0 {
+		t.Errorf("STATUS = %04X after clearing the vblank bit, want 0", status)
+	}
+}
+
+func TestGraphicsReset(t *testing.T) {
+	g := CreateGraphicsDevice(10, nil, 0)
+	g.SetUint8(0, 0x77)
+	g.SetUint16(graphicsPalette, 0x0F00)
+	g.SetUint16(graphicsCtrl, GraphicsCtrlVblankInterrupt)
+	g.Tick(15)
+	g.Reset()
+
+	if palette, ctrl, status := g.GetUint16(graphicsPalette), g.GetUint16(graphicsCtrl), g.GetUint16(graphicsStatus); palette != 0 || ctrl != 0 || status != 0 {
+		t.Errorf("palette 0, CTRL, STATUS = %04X, %04X, %04X after Reset, want 0", palette, ctrl, status)
+	}
+	if g.GetUint8(0) != 0x77 {
+		t.Error("Reset cleared the framebuffer")
+	}
+	g.Tick(9)
+	if status := g.GetUint16(graphicsStatus); status != 0 {
+		t.Errorf("STATUS = %04X 9 cycles after Reset, want the frame counter restarted", status)
+	}
+}
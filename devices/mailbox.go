@@ -0,0 +1,172 @@
+package devices
+
+import "fmt"
+
+//INFO: Mailbox device, connects several vm instances running in the same process.
+// Every vm maps its own endpoint, all endpoints created from the same hub can send words to each other.
+//
+// Register layout (16 bit registers, offsets relative to the mapped start):
+//
+//	+0x00 ID       id of this endpoint (read-only)
+//	+0x02 DEST     endpoint id the next TX goes to
+//	+0x04 TX       writing sends the word to DEST
+//	+0x06 RX       reading removes and returns the oldest received word (0 when empty)
+//	+0x08 RX_FROM  sender id of the oldest received word, read it before RX
+//	+0x0A STATUS   bit0 a word is waiting, bit1 the last send failed (unknown id or full queue)
+//	+0x0C COUNT    number of words waiting
+//	+0x0E CTRL     bit0 raise an interupt when a word arrives
+
+const (
+	MailboxSize          = 0x10
+	MailboxQueueCapacity = 64
+
+	MailboxStatusReady      = 1 << 0
+	MailboxStatusSendFailed = 1 << 1
+	MailboxCtrlInterrupt    = 1 << 0
+
+	mailboxID     = 0x00
+	mailboxDest   = 0x02
+	mailboxTx     = 0x04
+	mailboxRx     = 0x06
+	mailboxRxFrom = 0x08
+	mailboxStatus = 0x0A
+	mailboxCount  = 0x0C
+	mailboxCtrl   = 0x0E
+)
+
+type mailboxMessage struct {
+	from  uint16
+	value uint16
+}
+
+// MailboxHub connects the endpoints of several vms
+type MailboxHub struct {
+	endpoints map[uint16]*MailboxDevice
+}
+
+func NewMailboxHub() *MailboxHub {
+	return &MailboxHub{endpoints: make(map[uint16]*MailboxDevice)}
+}
+
+// CreateEndpoint creates the mailbox device of one vm.
+// interupt is called with interuptValue when a word arrives and CTRL enables it, usually cpu.RequestInterupt. It may be nil.
+// Closing the endpoint frees its id again
+func (h *MailboxHub) CreateEndpoint(id uint16, interupt func(uint16), interuptValue uint16) (*MailboxDevice, error) {
+	if _, exists := h.endpoints[id]; exists {
+		return nil, fmt.Errorf("mailbox: endpoint id %d already in use", id)
+	}
+	endpoint := &MailboxDevice{
+		hub:           h,
+		id:            id,
+		interupt:      interupt,
+		interuptValue: interuptValue,
+	}
+	h.endpoints[id] = endpoint
+	return endpoint, nil
+}
+
+// Send delivers a word from one endpoint to another, it is also used by go code to inject messages
+func (h *MailboxHub) Send(from, to, value uint16) bool {
+	endpoint, ok := h.endpoints[to]
+	if !ok || len(endpoint.queue) >= MailboxQueueCapacity {
+		return false
+	}
+	endpoint.queue = append(endpoint.queue, mailboxMessage{from: from, value: value})
+	if endpoint.ctrl&MailboxCtrlInterrupt != 0 && endpoint.interupt != nil {
+		endpoint.interupt(endpoint.interuptValue)
+	}
+	return true
+}
+
+// MailboxDevice is the endpoint a single vm maps into its address space
+type MailboxDevice struct {
+	hub           *MailboxHub
+	id            uint16
+	dest          uint16
+	ctrl          uint16
+	sendFailed    bool
+	queue         []mailboxMessage
+	interupt      func(uint16)
+	interuptValue uint16
+	latch         registerLatch
+}
+
+// Reset drops the waiting words and clears the registers
+func (m *MailboxDevice) Reset() {
+	m.queue = nil
+	m.dest = 0
+	m.ctrl = 0
+	m.sendFailed = false
+	m.latch = registerLatch{}
+}
+
+// Close removes the endpoint from its hub, words sent to its id fail from now on
+func (m *MailboxDevice) Close() error {
+	if m.hub.endpoints[m.id] == m {
+		delete(m.hub.endpoints, m.id)
+	}
+	m.queue = nil
+	return nil
+}
+
+func (m *MailboxDevice) GetUint16(address int) uint16 {
+	switch address &^ 1 {
+	case mailboxID:
+		return m.id
+	case mailboxDest:
+		return m.dest
+	case mailboxRx:
+		if len(m.queue) == 0 {
+			return 0
+		}
+		message := m.queue[0]
+		m.queue = m.queue[1:]
+		return message.value
+	case mailboxRxFrom:
+		if len(m.queue) == 0 {
+			return 0
+		}
+		return m.queue[0].from
+	case mailboxStatus:
+		var status uint16
+		if len(m.queue) > 0 {
+			status |= MailboxStatusReady
+		}
+		if m.sendFailed {
+			status |= MailboxStatusSendFailed
+		}
+		return status
+	case mailboxCount:
+		return uint16(len(m.queue))
+	case mailboxCtrl:
+		return m.ctrl
+	}
+	return 0
+}
+
+func (m *MailboxDevice) SetUint16(address int, value uint16) {
+	switch address &^ 1 {
+	case mailboxDest:
+		m.dest = value
+	case mailboxTx:
+		m.sendFailed = !m.hub.Send(m.id, m.dest, value)
+	case mailboxCtrl:
+		m.ctrl = value
+	}
+}
+
+func (m *MailboxDevice) GetUint8(address int) uint8 {
+	if address&^1 == mailboxRx {
+		return m.latch.readByte(m, address) // both bytes come from the same word
+	}
+	return registerByte(m, address)
+}
+
+func (m *MailboxDevice) SetUint8(address int, value uint8) {
+	switch address &^ 1 {
+	case mailboxDest, mailboxCtrl:
+		setRegisterByte(m, address, value)
+	case mailboxTx:
+		m.latch.writeByte(m, address, value) // the word is sent with its low byte
+	}
+}
@@ -0,0 +1,106 @@
+package devices
+
+import "testing"
+
+func newTestMailboxes(t *testing.T) (*MailboxHub, *MailboxDevice, *MailboxDevice) {
+	t.Helper()
+	hub := NewMailboxHub()
+	a, err := hub.CreateEndpoint(1, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := hub.CreateEndpoint(2, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hub, a, b
+}
+
+func TestMailboxOrder(t *testing.T) {
+	_, a, b := newTestMailboxes(t)
+	a.SetUint16(mailboxDest, 2)
+	for _, value := range []uint16{0x1111, 0x2222, 0x3333} {
+		a.SetUint16(mailboxTx, value)
+	}
+
+	if count := b.GetUint16(mailboxCount); count != 3 {
+		t.Fatalf("COUNT = %d, want 3", count)
+	}
+	for _, want := range []uint16{0x1111, 0x2222, 0x3333} {
+		if from := b.GetUint16(mailboxRxFrom); from != 1 {
+			t.Errorf("RX_FROM = %d, want 1", from)
+		}
+		if got := b.GetUint16(mailboxRx); got != want {
+			t.Errorf("RX = %04X, want %04X", got, want)
+		}
+	}
+	if status := b.GetUint16(mailboxStatus); status != 0 {
+		t.Errorf("STATUS = %04X after draining, want 0", status)
+	}
+}
+
+func TestMailboxSendFailed(t *testing.T) {
+	hub, a, _ := newTestMailboxes(t)
+	a.SetUint16(mailboxDest, 9)
+	a.SetUint16(mailboxTx, 1)
+	if a.GetUint16(mailboxStatus)&MailboxStatusSendFailed == 0 {
+		t.Error("sending to an unknown id did not set the send failed bit")
+	}
+
+	for i := 0; i < MailboxQueueCapacity; i++ {
+		hub.Send(1, 2, uint16(i))
+	}
+	if hub.Send(1, 2, 0) {
+		t.Error("send to a full queue succeeded")
+	}
+}
+
+func TestMailboxByteAccess(t *testing.T) {
+	_, a, b := newTestMailboxes(t)
+	a.SetUint8(mailboxDest+1, 2)
+	a.SetUint8(mailboxTx, 0x12)
+	a.SetUint8(mailboxTx+1, 0x34)
+	a.SetUint16(mailboxTx, 0x5678)
+
+	if count := b.GetUint16(mailboxCount); count != 2 {
+		t.Fatalf("COUNT = %d after a byte and a word send, want 2", count)
+	}
+
+	b.SetUint8(mailboxRx, 0xFF) // read-only, must not drop a word
+	b.SetUint8(mailboxRx+1, 0xFF)
+	high, low := b.GetUint8(mailboxRx), b.GetUint8(mailboxRx+1)
+	if high != 0x12 || low != 0x34 {
+		t.Errorf("RX bytes = %02X %02X, want 12 34", high, low)
+	}
+	if got := b.GetUint16(mailboxRx); got != 0x5678 {
+		t.Errorf("second word = %04X, want 5678", got)
+	}
+}
+
+func TestMailboxEndpointID(t *testing.T) {
+	hub, a, _ := newTestMailboxes(t)
+	if _, err := hub.CreateEndpoint(1, nil, 0); err == nil {
+		t.Fatal("a second endpoint with id 1 was created")
+	}
+
+	a.Close()
+	if hub.Send(2, 1, 0) {
+		t.Error("send to a closed endpoint succeeded")
+	}
+	if _, err := hub.CreateEndpoint(1, nil, 0); err != nil {
+		t.Errorf("id 1 is not free after Close: %v", err)
+	}
+}
+
+func TestMailboxInterrupt(t *testing.T) {
+	hub := NewMailboxHub()
+	var raised []uint16
+	b, _ := hub.CreateEndpoint(2, func(value uint16) { raised = append(raised, value) }, 7)
+
+	hub.Send(1, 2, 0)
+	b.SetUint16(mailboxCtrl, MailboxCtrlInterrupt)
+	hub.Send(1, 2, 0)
+	if len(raised) != 1 || raised[0] != 7 {
+		t.Errorf("interrupts = %v, want [7]", raised)
+	}
+}
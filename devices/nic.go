@@ -0,0 +1,242 @@
+package devices
+
+import (
+	memorymapper "github.com/martbul/memoryMapper"
+)
+
+//INFO: Network interface. Packets are moved through descriptor rings in guest memory, the host side is a PacketTransport.
+// A frame starts with the destination address (16 bits) followed by the source address, the rest is payload.
+// Address 0xFFFF is broadcast.
+//
+// A descriptor is 3 words: { buffer address, length, flags }. flags bit15 (OWN) is set when the descriptor belongs to the nic.
+//   - tx: the guest fills buffer/length and sets OWN, the nic sends the frame, clears OWN and sets bit0 (DONE)
+//   - rx: the guest sets buffer/length (= capacity) and OWN, the nic copies a frame in, stores the real length and clears OWN
+//
+// Register layout (16 bit registers, offsets relative to the mapped start):
+//
+//	+0x00 MAC       address of this interface
+//	+0x02 CTRL      bit0 enable, bit1 rx interupt, bit2 tx interupt, bit3 promiscuous (accept every destination)
+//	+0x04 STATUS    bit0 frame received, bit1 frame sent, bit2 frame dropped (no rx descriptor). Writing a 1 clears
+//	+0x06 TX_RING   guest address of the tx ring
+//	+0x08 TX_COUNT  descriptors in the tx ring
+//	+0x0A RX_RING   guest address of the rx ring
+//	+0x0C RX_COUNT  descriptors in the rx ring
+//	+0x0E TX_INDEX  next tx descriptor the nic looks at (writing resets it)
+//	+0x10 RX_INDEX  next rx descriptor the nic fills (writing resets it)
+
+const (
+	NICSize           = 0x12
+	NICDescriptorSize = 6
+	NICBroadcast      = 0xFFFF
+
+	NICCtrlEnable      = 1 << 0
+	NICCtrlRxInterrupt = 1 << 1
+	NICCtrlTxInterrupt = 1 << 2
+	NICCtrlPromiscuous = 1 << 3
+
+	NICStatusReceived = 1 << 0
+	NICStatusSent     = 1 << 1
+	NICStatusDropped  = 1 << 2
+
+	NICDescriptorOwn  = 1 << 15
+	NICDescriptorDone = 1 << 0
+
+	nicMac     = 0x00
+	nicCtrl    = 0x02
+	nicStatus  = 0x04
+	nicTxRing  = 0x06
+	nicTxCount = 0x08
+	nicRxRing  = 0x0A
+	nicRxCount = 0x0C
+	nicTxIndex = 0x0E
+	nicRxIndex = 0x10
+)
+
+// PacketTransport is the host side of a nic
+type PacketTransport interface {
+	Send(frame []byte) error
+	// Receive returns the next waiting frame without blocking
+	Receive() ([]byte, bool)
+	Close() error
+}
+
+// NICDevice is a network interface with descriptor rings in guest memory
+type NICDevice struct {
+	memory        *memorymapper.MemoryMapper
+	transport     PacketTransport
+	interupt      func(uint16)
+	interuptValue uint16
+
+	mac     uint16
+	ctrl    uint16
+	status  uint16
+	txRing  uint16
+	txCount uint16
+	rxRing  uint16
+	rxCount uint16
+	txIndex uint16
+	rxIndex uint16
+	latch   registerLatch
+}
+
+// CreateNICDevice creates an interface with the given address on top of transport.
+// interupt is called with interuptValue when enabled in CTRL, usually cpu.RequestInterupt. It may be nil
+func CreateNICDevice(memory *memorymapper.MemoryMapper, transport PacketTransport, mac uint16, interupt func(uint16), interuptValue uint16) *NICDevice {
+	return &NICDevice{
+		memory:        memory,
+		transport:     transport,
+		mac:           mac,
+		interupt:      interupt,
+		interuptValue: interuptValue,
+	}
+}
+
+// Close closes the transport
+func (n *NICDevice) Close() error {
+	return n.transport.Close()
+}
+
+// Reset disables the interface and forgets the rings, the address is kept
+func (n *NICDevice) Reset() {
+	n.ctrl, n.status = 0, 0
+	n.txRing, n.txCount, n.txIndex = 0, 0, 0
+	n.rxRing, n.rxCount, n.rxIndex = 0, 0, 0
+	n.latch = registerLatch{}
+}
+
+func (n *NICDevice) GetUint16(address int) uint16 {
+	switch address &^ 1 {
+	case nicMac:
+		return n.mac
+	case nicCtrl:
+		return n.ctrl
+	case nicStatus:
+		return n.status
+	case nicTxRing:
+		return n.txRing
+	case nicTxCount:
+		return n.txCount
+	case nicRxRing:
+		return n.rxRing
+	case nicRxCount:
+		return n.rxCount
+	case nicTxIndex:
+		return n.txIndex
+	case nicRxIndex:
+		return n.rxIndex
+	}
+	return 0
+}
+
+func (n *NICDevice) SetUint16(address int, value uint16) {
+	switch address &^ 1 {
+	case nicMac:
+		n.mac = value
+	case nicCtrl:
+		n.ctrl = value
+	case nicStatus:
+		n.status &^= value
+	case nicTxRing:
+		n.txRing = value
+	case nicTxCount:
+		n.txCount = value
+	case nicRxRing:
+		n.rxRing = value
+	case nicRxCount:
+		n.rxCount = value
+	case nicTxIndex:
+		n.txIndex = value
+	case nicRxIndex:
+		n.rxIndex = value
+	}
+}
+
+func (n *NICDevice) GetUint8(address int) uint8 {
+	return registerByte(n, address)
+}
+
+func (n *NICDevice) SetUint8(address int, value uint8) {
+	if address&^1 == nicStatus {
+		n.latch.writeByte(n, address, value) // STATUS is write 1 to clear
+		return
+	}
+	setRegisterByte(n, address, value)
+}
+
+// Tick sends at most one pending tx descriptor and receives at most one frame per cycle
+func (n *NICDevice) Tick(cycles int) {
+	if n.ctrl&NICCtrlEnable == 0 {
+		return
+	}
+	for i := 0; i < cycles; i++ {
+		n.transmit()
+		n.receive()
+	}
+}
+
+func (n *NICDevice) transmit() {
+	if n.txCount == 0 {
+		return
+	}
+	descriptor := int(n.txRing) + int(n.txIndex%n.txCount)*NICDescriptorSize
+	flags, _ := n.memory.GetUint16(descriptor + 4)
+	if flags&NICDescriptorOwn == 0 {
+		return
+	}
+
+	buffer, _ := n.memory.GetUint16(descriptor)
+	length, _ := n.memory.GetUint16(descriptor + 2)
+	frame := make([]byte, length)
+	for i := range frame {
+		frame[i], _ = n.memory.GetUint8(int(buffer) + i)
+	}
+	n.transport.Send(frame)
+
+	n.memory.SetUint16(descriptor+4, (flags&^NICDescriptorOwn)|NICDescriptorDone)
+	n.txIndex = (n.txIndex + 1) % n.txCount
+	n.raise(NICStatusSent, NICCtrlTxInterrupt)
+}
+
+func (n *NICDevice) receive() {
+	frame, ok := n.transport.Receive()
+	if !ok {
+		return
+	}
+	if len(frame) < 2 {
+		return
+	}
+	destination := uint16(frame[0])<<8 | uint16(frame[1])
+	if n.ctrl&NICCtrlPromiscuous == 0 && destination != n.mac && destination != NICBroadcast {
+		return
+	}
+
+	if n.rxCount == 0 {
+		n.status |= NICStatusDropped
+		return
+	}
+	descriptor := int(n.rxRing) + int(n.rxIndex%n.rxCount)*NICDescriptorSize
+	flags, _ := n.memory.GetUint16(descriptor + 4)
+	if flags&NICDescriptorOwn == 0 {
+		n.status |= NICStatusDropped
+		return
+	}
+
+	buffer, _ := n.memory.GetUint16(descriptor)
+	capacity, _ := n.memory.GetUint16(descriptor + 2)
+	length := min(len(frame), int(capacity))
+	for i := 0; i < length; i++ {
+		n.memory.SetUint8(int(buffer)+i, frame[i])
+	}
+
+	n.memory.SetUint16(descriptor+2, uint16(length))
+	n.memory.SetUint16(descriptor+4, (flags&^NICDescriptorOwn)|NICDescriptorDone)
+	n.rxIndex = (n.rxIndex + 1) % n.rxCount
+	n.raise(NICStatusReceived, NICCtrlRxInterrupt)
+}
+
+func (n *NICDevice) raise(status, interruptBit uint16) {
+	n.status |= status
+	if n.ctrl&interruptBit != 0 && n.interupt != nil {
+		n.interupt(n.interuptValue)
+	}
+}
@@ -0,0 +1,165 @@
+package devices
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/martbul/memory"
+	memorymapper "github.com/martbul/memoryMapper"
+)
+
+// nicTest is a nic with address 0x0A01 over 64k of ram, peer is the other
+// port of its switch. The tx ring is at 0x100 and the rx ring at 0x180, both
+// with two descriptors.
+type nicTest struct {
+	*NICDevice
+	ram    []byte
+	peer   *SwitchPort
+	raised int
+}
+
+func newNICTest(ctrl uint16) *nicTest {
+	ram := memory.CreateMemory(0x10000)
+	mapper := memorymapper.NewMemoryMapper()
+	mapper.Map(ram, 0, 0xffff)
+	sw := NewPacketSwitch()
+	test := &nicTest{ram: ram.GetBuffer()}
+	test.NICDevice = CreateNICDevice(mapper, sw.Port(), 0x0A01, func(uint16) { test.raised++ }, 0x40)
+	test.peer = sw.Port()
+	test.SetUint16(nicTxRing, 0x100)
+	test.SetUint16(nicTxCount, 2)
+	test.SetUint16(nicRxRing, 0x180)
+	test.SetUint16(nicRxCount, 2)
+	test.SetUint16(nicCtrl, ctrl)
+	return test
+}
+
+func (n *nicTest) word(address int) uint16 {
+	return uint16(n.ram[address])<<8 | uint16(n.ram[address+1])
+}
+
+func (n *nicTest) setWord(address int, value uint16) {
+	n.ram[address], n.ram[address+1] = byte(value>>8), byte(value)
+}
+
+// descriptor fills descriptor index of the ring at ring and hands it to the nic
+func (n *nicTest) descriptor(ring, index int, buffer, length uint16) {
+	at := ring + index*NICDescriptorSize
+	n.setWord(at, buffer)
+	n.setWord(at+2, length)
+	n.setWord(at+4, NICDescriptorOwn)
+}
+
+func TestNICTransmit(t *testing.T) {
+	n := newNICTest(NICCtrlEnable | NICCtrlTxInterrupt)
+	copy(n.ram[0x200:], []byte{0xFF, 0xFF, 0x0A, 0x01, 'h', 'i'})
+	n.descriptor(0x100, 0, 0x200, 6)
+	n.descriptor(0x100, 1, 0x200, 4)
+
+	n.Tick(1)
+	if frame, ok := n.peer.Receive(); !ok || !bytes.Equal(frame, n.ram[0x200:0x206]) {
+		t.Errorf("peer received % X, %v, want the first frame", frame, ok)
+	}
+	if flags := n.word(0x104); flags != NICDescriptorDone {
+		t.Errorf("descriptor 0 flags = %04X, want DONE without OWN", flags)
+	}
+	if index, status := n.GetUint16(nicTxIndex), n.GetUint16(nicStatus); index != 1 || status != NICStatusSent || n.raised != 1 {
+		t.Errorf("TX_INDEX = %d, STATUS = %04X with %d interrupts, want 1, %04X and 1", index, status, n.raised, NICStatusSent)
+	}
+
+	n.Tick(2) // the second frame, then the ring wraps to descriptor 0 which the nic doesn't own
+	if frame, ok := n.peer.Receive(); !ok || len(frame) != 4 {
+		t.Errorf("peer received % X, %v, want the 4 byte frame", frame, ok)
+	}
+	if _, ok := n.peer.Receive(); ok {
+		t.Error("a descriptor was sent twice")
+	}
+	if index := n.GetUint16(nicTxIndex); index != 0 {
+		t.Errorf("TX_INDEX = %d, want the ring to wrap to 0", index)
+	}
+}
+
+func TestNICReceive(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctrl     uint16
+		frame    []byte
+		capacity uint16
+		want     []byte // what lands in the buffer, nil when the frame is ignored
+		status   uint16
+	}{
+		{"own address", 0, []byte{0x0A, 0x01, 0x0B, 0x01, 1, 2}, 0x20, []byte{0x0A, 0x01, 0x0B, 0x01, 1, 2}, NICStatusReceived},
+		{"broadcast", 0, []byte{0xFF, 0xFF, 0x0B, 0x01}, 0x20, []byte{0xFF, 0xFF, 0x0B, 0x01}, NICStatusReceived},
+		{"other address", 0, []byte{0x0C, 0x01, 0x0B, 0x01}, 0x20, nil, 0},
+		{"promiscuous", NICCtrlPromiscuous, []byte{0x0C, 0x01, 0x0B, 0x01}, 0x20, []byte{0x0C, 0x01, 0x0B, 0x01}, NICStatusReceived},
+		{"truncated", 0, []byte{0x0A, 0x01, 0x0B, 0x01, 1, 2, 3}, 5, []byte{0x0A, 0x01, 0x0B, 0x01, 1}, NICStatusReceived},
+		{"runt", 0, []byte{0x0A}, 0x20, nil, 0},
+		{"no descriptor", 0, []byte{0x0A, 0x01}, 0, nil, NICStatusDropped},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n := newNICTest(NICCtrlEnable | NICCtrlRxInterrupt | test.ctrl)
+			if test.capacity > 0 {
+				n.descriptor(0x180, 0, 0x300, test.capacity)
+			}
+			n.peer.Send(test.frame)
+			n.Tick(1)
+
+			if status := n.GetUint16(nicStatus); status != test.status {
+				t.Errorf("STATUS = %04X, want %04X", status, test.status)
+			}
+			if test.want == nil {
+				if n.GetUint16(nicRxIndex) != 0 || n.raised != 0 {
+					t.Errorf("RX_INDEX = %d with %d interrupts, want the frame ignored", n.GetUint16(nicRxIndex), n.raised)
+				}
+				return
+			}
+			if got := n.ram[0x300 : 0x300+len(test.want)+1]; !bytes.Equal(got, append(test.want, 0)) {
+				t.Errorf("buffer = % X, want % X", got, test.want)
+			}
+			if length, flags := n.word(0x182), n.word(0x184); int(length) != len(test.want) || flags != NICDescriptorDone {
+				t.Errorf("descriptor length %d, flags %04X, want %d and DONE", length, flags, len(test.want))
+			}
+			if index := n.GetUint16(nicRxIndex); index != 1 || n.raised != 1 {
+				t.Errorf("RX_INDEX = %d with %d interrupts, want 1 and 1", index, n.raised)
+			}
+		})
+	}
+}
+
+func TestNICDisabled(t *testing.T) {
+	n := newNICTest(0)
+	n.descriptor(0x100, 0, 0x200, 2)
+	n.descriptor(0x180, 0, 0x300, 2)
+	n.peer.Send([]byte{0x0A, 0x01})
+	n.Tick(10)
+	if _, ok := n.peer.Receive(); ok || n.GetUint16(nicStatus) != 0 || n.word(0x184) != NICDescriptorOwn {
+		t.Error("a disabled nic moved frames")
+	}
+}
+
+func TestNICStatusByteWrite(t *testing.T) {
+	n := newNICTest(NICCtrlEnable)
+	n.peer.Send([]byte{0x0A, 0x01})
+	n.descriptor(0x100, 0, 0x200, 2)
+	n.Tick(1) // sent, and dropped as no rx descriptor is owned
+	if status := n.GetUint16(nicStatus); status != NICStatusSent|NICStatusDropped {
+		t.Fatalf("STATUS = %04X, want sent and dropped", status)
+	}
+
+	n.SetUint8(nicStatus, 0)
+	n.SetUint8(nicStatus+1, NICStatusDropped)
+	if status := n.GetUint16(nicStatus); status != NICStatusSent {
+		t.Errorf("STATUS = %04X after clearing dropped, want sent", status)
+	}
+	n.SetUint8(nicStatus+1, 0)
+	if status := n.GetUint16(nicStatus); status != NICStatusSent {
+		t.Errorf("STATUS = %04X after writing 0, want sent", status)
+	}
+
+	n.SetUint8(nicMac, 0x0B)
+	n.SetUint8(nicMac+1, 0x02)
+	if mac := n.GetUint16(nicMac); mac != 0x0B02 {
+		t.Errorf("MAC = %04X after byte writes, want 0B02", mac)
+	}
+}
@@ -0,0 +1,99 @@
+package devices
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+)
+
+//INFO: Random number generator. A seedable xorshift64* generator, the same seed always produces the same words
+// so a recorded run can be replayed by reusing Seed().
+//
+// Register layout (16 bit registers, offsets relative to the mapped start):
+//
+//	+0x00 VALUE  every read returns the next pseudo random word, reading the high byte then the low byte uses up one word
+//	+0x02 SEED   writing reseeds the generator with the value (from the guest)
+
+const (
+	RandomSize = 0x04
+
+	randomValue = 0x00
+	randomSeed  = 0x02
+)
+
+// RandomDevice returns pseudo random words
+type RandomDevice struct {
+	seed  uint64
+	state uint64
+	latch registerLatch
+}
+
+// CreateRandomDevice creates a deterministic generator, e.g. with a seed from the machine configuration
+func CreateRandomDevice(seed uint64) *RandomDevice {
+	r := &RandomDevice{}
+	r.Reseed(seed)
+	return r
+}
+
+// CreateHostRandomDevice seeds the generator from the host's entropy source, Seed() returns the seed for replays
+func CreateHostRandomDevice() *RandomDevice {
+	var buffer [8]byte
+	rand.Read(buffer[:])
+	return CreateRandomDevice(binary.BigEndian.Uint64(buffer[:]))
+}
+
+// Seed returns the seed the generator was last seeded with
+func (r *RandomDevice) Seed() uint64 {
+	return r.seed
+}
+
+// Reseed restarts the sequence from seed
+func (r *RandomDevice) Reseed(seed uint64) {
+	r.seed = seed
+	r.state = seed
+	if r.state == 0 {
+		r.state = 0x9E3779B97F4A7C15 // xorshift gets stuck on 0
+	}
+}
+
+// Reset restarts the sequence from the last seed, a reset run produces the same numbers again
+func (r *RandomDevice) Reset() {
+	r.Reseed(r.seed)
+	r.latch = registerLatch{}
+}
+
+// Next returns the next pseudo random word
+func (r *RandomDevice) Next() uint16 {
+	r.state ^= r.state >> 12
+	r.state ^= r.state << 25
+	r.state ^= r.state >> 27
+	return uint16((r.state * 0x2545F4914F6CDD1D) >> 48)
+}
+
+func (r *RandomDevice) GetUint16(address int) uint16 {
+	switch address &^ 1 {
+	case randomValue:
+		return r.Next()
+	case randomSeed:
+		return uint16(r.seed)
+	}
+	return 0
+}
+
+func (r *RandomDevice) SetUint16(address int, value uint16) {
+	if address&^1 == randomSeed {
+		r.Reseed(uint64(value))
+	}
+}
+
+func (r *RandomDevice) GetUint8(address int) uint8 {
+	if address&^1 == randomValue {
+		return r.latch.readByte(r, address)
+	}
+	return registerByte(r, address)
+}
+
+func (r *RandomDevice) SetUint8(address int, value uint8) {
+	if address&^1 == randomSeed {
+		setRegisterByte(r, address, value)
+	}
+}
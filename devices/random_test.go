@@ -0,0 +1,57 @@
+package devices
+
+import "testing"
+
+func TestRandomSeedReplays(t *testing.T) {
+	a, b := CreateRandomDevice(42), CreateRandomDevice(42)
+	for i := 0; i < 100; i++ {
+		if x, y := a.GetUint16(randomValue), b.GetUint16(randomValue); x != y {
+			t.Fatalf("word %d differs: %04X %04X", i, x, y)
+		}
+	}
+
+	first := CreateRandomDevice(7).Next()
+	r := CreateRandomDevice(7)
+	r.Next()
+	r.Reset()
+	if got := r.Next(); got != first {
+		t.Errorf("after Reset got %04X, want %04X", got, first)
+	}
+
+	r.SetUint16(randomSeed, 7)
+	if got := r.Next(); got != first {
+		t.Errorf("after writing SEED got %04X, want %04X", got, first)
+	}
+	if seed := r.GetUint16(randomSeed); seed != 7 {
+		t.Errorf("SEED = %d, want 7", seed)
+	}
+}
+
+func TestRandomZeroSeed(t *testing.T) {
+	r := CreateRandomDevice(0)
+	if r.Next() == 0 && r.Next() == 0 {
+		t.Error("a zero seed is stuck at 0")
+	}
+}
+
+func TestRandomByteAccess(t *testing.T) {
+	words := CreateRandomDevice(42)
+	bytes := CreateRandomDevice(42)
+
+	for i := 0; i < 10; i++ {
+		bytes.SetUint8(randomValue, 0xFF) // read-only, must not advance the generator
+		bytes.SetUint8(randomValue+1, 0xFF)
+
+		want := words.GetUint16(randomValue)
+		got := uint16(bytes.GetUint8(randomValue))<<8 | uint16(bytes.GetUint8(randomValue+1))
+		if got != want {
+			t.Fatalf("word %d read as bytes = %04X, want %04X", i, got, want)
+		}
+	}
+
+	bytes.SetUint8(randomSeed, 0x12)
+	bytes.SetUint8(randomSeed+1, 0x34)
+	if seed := bytes.Seed(); seed != 0x1234 {
+		t.Errorf("seed written as bytes = %04X, want 1234", seed)
+	}
+}
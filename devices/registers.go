@@ -0,0 +1,75 @@
+package devices
+
+//INFO: Most devices expose 16 bit registers, the helpers below let them answer byte sized accesses as well.
+// Registers are big endian like the rest of the vm -> the even address is the high byte
+
+type wordRegisters interface {
+	GetUint16(address int) uint16
+	SetUint16(address int, value uint16)
+}
+
+// registerByte reads one byte of the 16 bit register that contains the address.
+// Only for registers without side effects on read, see registerLatch
+func registerByte(device wordRegisters, address int) uint8 {
+	word := device.GetUint16(address &^ 1)
+	if address%2 == 0 {
+		return uint8(word >> 8)
+	}
+	return uint8(word)
+}
+
+// setRegisterByte replaces one byte of the 16 bit register that contains the address.
+// It reads the register back, so only for plain registers, see registerLatch
+func setRegisterByte(device wordRegisters, address int, value uint8) {
+	word := device.GetUint16(address &^ 1)
+	if address%2 == 0 {
+		word = (word & 0x00FF) | uint16(value)<<8
+	} else {
+		word = (word & 0xFF00) | uint16(value)
+	}
+	device.SetUint16(address&^1, word)
+}
+
+// registerLatch turns the two byte accesses to a register with side effects (a command, a fifo,
+// write 1 to clear status bits) into a single word access, high byte first:
+//   - reading the high byte reads the register once, the following read of the low byte returns the rest of that word
+//   - writing the high byte only keeps it, writing the low byte writes the whole word
+//
+// A low byte access on its own reads the register or writes it with a high byte of 0. The register is never
+// read on the write path.
+type registerLatch struct {
+	reading       bool // the low byte of readRegister is waiting
+	readRegister  int
+	readLow       uint8
+	writing       bool // the high byte of writeRegister is waiting
+	writeRegister int
+	writeHigh     uint8
+}
+
+func (l *registerLatch) readByte(device wordRegisters, address int) uint8 {
+	register := address &^ 1
+	if address%2 == 0 {
+		word := device.GetUint16(register)
+		l.reading, l.readRegister, l.readLow = true, register, uint8(word)
+		return uint8(word >> 8)
+	}
+	if l.reading && l.readRegister == register {
+		l.reading = false
+		return l.readLow
+	}
+	return uint8(device.GetUint16(register))
+}
+
+func (l *registerLatch) writeByte(device wordRegisters, address int, value uint8) {
+	register := address &^ 1
+	if address%2 == 0 {
+		l.writing, l.writeRegister, l.writeHigh = true, register, value
+		return
+	}
+	var high uint8
+	if l.writing && l.writeRegister == register {
+		high = l.writeHigh
+	}
+	l.writing = false
+	device.SetUint16(register, uint16(high)<<8|uint16(value))
+}
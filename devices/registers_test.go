@@ -0,0 +1,76 @@
+package devices
+
+import (
+	"slices"
+	"testing"
+)
+
+// recordingRegisters logs every word access
+type recordingRegisters struct {
+	word   uint16
+	reads  int
+	writes []uint16
+}
+
+func (r *recordingRegisters) GetUint16(address int) uint16 {
+	r.reads++
+	return r.word
+}
+
+func (r *recordingRegisters) SetUint16(address int, value uint16) {
+	r.writes = append(r.writes, value)
+}
+
+func TestRegisterLatchWrite(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes [][2]int // address, value
+		want   []uint16
+	}{
+		{"high then low", [][2]int{{0, 0x12}, {1, 0x34}}, []uint16{0x1234}},
+		{"low only", [][2]int{{1, 0x34}}, []uint16{0x0034}},
+		{"high only", [][2]int{{0, 0x12}}, nil},
+		{"high of another register", [][2]int{{2, 0x12}, {1, 0x34}}, []uint16{0x0034}},
+		{"two words", [][2]int{{0, 0x12}, {1, 0x34}, {0, 0x56}, {1, 0x78}}, []uint16{0x1234, 0x5678}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			device := &recordingRegisters{word: 0xFFFF}
+			var latch registerLatch
+			for _, write := range test.writes {
+				latch.writeByte(device, write[0], uint8(write[1]))
+			}
+			if !slices.Equal(device.writes, test.want) {
+				t.Errorf("wrote %04X, want %04X", device.writes, test.want)
+			}
+			if device.reads != 0 {
+				t.Errorf("read the register %d times on the write path", device.reads)
+			}
+		})
+	}
+}
+
+func TestRegisterLatchRead(t *testing.T) {
+	device := &recordingRegisters{word: 0x1234}
+	var latch registerLatch
+
+	high := latch.readByte(device, 0)
+	device.word = 0x5678
+	low := latch.readByte(device, 1)
+	if high != 0x12 || low != 0x34 || device.reads != 1 {
+		t.Errorf("got %02X %02X with %d reads, want 12 34 with 1 read", high, low, device.reads)
+	}
+
+	if low := latch.readByte(device, 1); low != 0x78 || device.reads != 2 {
+		t.Errorf("low byte on its own: got %02X with %d reads, want 78 with 2 reads", low, device.reads)
+	}
+}
+
+func TestSetRegisterByte(t *testing.T) {
+	device := &recordingRegisters{word: 0x1234}
+	setRegisterByte(device, 0, 0xAB)
+	setRegisterByte(device, 1, 0xCD)
+	if want := []uint16{0xAB34, 0x12CD}; !slices.Equal(device.writes, want) {
+		t.Errorf("wrote %04X, want %04X", device.writes, want)
+	}
+}
@@ -0,0 +1,140 @@
+package devices
+
+import "time"
+
+//INFO: Real time clock.
+//
+// Register layout (16 bit registers, offsets relative to the mapped start):
+//
+//	+0x00 SECONDS   reading it latches the whole date/time, read it first
+//	+0x02 MINUTES
+//	+0x04 HOURS
+//	+0x06 DAY
+//	+0x08 MONTH
+//	+0x0A YEAR      full year (2026 or $2026 in bcd mode)
+//	+0x0C CTRL      bit0 bcd mode (0 = binary)
+//	+0x0E MILLIS_HI high word of the free running millisecond counter, reading it latches MILLIS_LO.
+//	                The counter starts at 0 when the device is created and never goes below it
+//	+0x10 MILLIS_LO
+//
+// The date/time fields are read-only, writes to them are ignored.
+
+const (
+	RTCCtrlBCD = 1 << 0
+
+	RTCSize = 0x12
+
+	rtcSeconds  = 0x00
+	rtcMinutes  = 0x02
+	rtcHours    = 0x04
+	rtcDay      = 0x06
+	rtcMonth    = 0x08
+	rtcYear     = 0x0A
+	rtcCtrl     = 0x0C
+	rtcMillisHi = 0x0E
+	rtcMillisLo = 0x10
+)
+
+// RTCDevice exposes the host (or an injected) wall clock to the guest
+type RTCDevice struct {
+	now      func() time.Time
+	start    time.Time
+	ctrl     uint16
+	latched  time.Time
+	millisLo uint16
+	latch    registerLatch
+
+	fixed time.Time
+}
+
+// CreateRTCDevice creates a clock that follows the host time
+func CreateRTCDevice() *RTCDevice {
+	rtc := &RTCDevice{now: time.Now}
+	rtc.start = rtc.now()
+	rtc.latched = rtc.start
+	return rtc
+}
+
+// CreateFixedRTCDevice creates a deterministic clock that only moves with SetTime and Advance
+func CreateFixedRTCDevice(t time.Time) *RTCDevice {
+	rtc := &RTCDevice{fixed: t, start: t, latched: t}
+	rtc.now = func() time.Time { return rtc.fixed }
+	return rtc
+}
+
+// SetTime injects the current time of a fixed clock, the millisecond counter keeps counting from the creation time
+// and stays at 0 while the time is before it
+func (r *RTCDevice) SetTime(t time.Time) {
+	r.fixed = t
+}
+
+// Advance moves a fixed clock forward
+func (r *RTCDevice) Advance(d time.Duration) {
+	r.fixed = r.fixed.Add(d)
+}
+
+func (r *RTCDevice) GetUint16(address int) uint16 {
+	switch address &^ 1 {
+	case rtcSeconds:
+		r.latched = r.now()
+		return r.encode(r.latched.Second())
+	case rtcMinutes:
+		return r.encode(r.latched.Minute())
+	case rtcHours:
+		return r.encode(r.latched.Hour())
+	case rtcDay:
+		return r.encode(r.latched.Day())
+	case rtcMonth:
+		return r.encode(int(r.latched.Month()))
+	case rtcYear:
+		return r.encode(r.latched.Year())
+	case rtcCtrl:
+		return r.ctrl
+	case rtcMillisHi:
+		millis := uint32(max(r.now().Sub(r.start).Milliseconds(), 0))
+		r.millisLo = uint16(millis)
+		return uint16(millis >> 16)
+	case rtcMillisLo:
+		return r.millisLo
+	}
+	return 0
+}
+
+func (r *RTCDevice) SetUint16(address int, value uint16) {
+	if address&^1 == rtcCtrl {
+		r.ctrl = value
+	}
+}
+
+func (r *RTCDevice) GetUint8(address int) uint8 {
+	switch address &^ 1 {
+	case rtcSeconds, rtcMillisHi:
+		return r.latch.readByte(r, address) // reading them latches the other fields, once per word
+	}
+	return registerByte(r, address)
+}
+
+func (r *RTCDevice) SetUint8(address int, value uint8) {
+	if address&^1 == rtcCtrl {
+		setRegisterByte(r, address, value)
+	}
+}
+
+// Reset switches back to binary mode
+func (r *RTCDevice) Reset() {
+	r.ctrl = 0
+	r.latch = registerLatch{}
+}
+
+// encode returns the value as binary or as 4 bcd digits depending on CTRL
+func (r *RTCDevice) encode(value int) uint16 {
+	if r.ctrl&RTCCtrlBCD == 0 {
+		return uint16(value)
+	}
+	var bcd uint16
+	for shift := 0; shift < 16; shift += 4 {
+		bcd |= uint16(value%10) << shift
+		value /= 10
+	}
+	return bcd
+}
@@ -0,0 +1,75 @@
+package devices
+
+import (
+	"testing"
+	"time"
+)
+
+var rtcEpoch = time.Date(2026, time.March, 14, 15, 9, 26, 0, time.UTC)
+
+func TestRTCFields(t *testing.T) {
+	tests := []struct {
+		ctrl uint16
+		want map[int]uint16
+	}{
+		{0, map[int]uint16{rtcSeconds: 26, rtcMinutes: 9, rtcHours: 15, rtcDay: 14, rtcMonth: 3, rtcYear: 2026}},
+		{RTCCtrlBCD, map[int]uint16{rtcSeconds: 0x26, rtcMinutes: 0x09, rtcHours: 0x15, rtcDay: 0x14, rtcMonth: 0x03, rtcYear: 0x2026}},
+	}
+	for _, test := range tests {
+		r := CreateFixedRTCDevice(rtcEpoch)
+		r.SetUint16(rtcCtrl, test.ctrl)
+		r.GetUint16(rtcSeconds)
+		for register, want := range test.want {
+			if got := r.GetUint16(register); got != want {
+				t.Errorf("ctrl %d: register 0x%02X = %04X, want %04X", test.ctrl, register, got, want)
+			}
+		}
+	}
+}
+
+func TestRTCLatch(t *testing.T) {
+	r := CreateFixedRTCDevice(rtcEpoch)
+	r.GetUint16(rtcSeconds)
+	r.Advance(time.Hour)
+	if hours := r.GetUint16(rtcHours); hours != 15 {
+		t.Errorf("HOURS = %d, want the latched 15", hours)
+	}
+
+	r.SetUint16(rtcSeconds, 0) // read-only, must not latch the new time
+	r.SetUint8(rtcSeconds, 0)
+	if hours := r.GetUint16(rtcHours); hours != 15 {
+		t.Errorf("HOURS = %d after writing SECONDS, want the latched 15", hours)
+	}
+
+	high := r.GetUint8(rtcSeconds)
+	r.Advance(time.Second)
+	low := r.GetUint8(rtcSeconds + 1)
+	if high != 0 || low != 26 {
+		t.Errorf("SECONDS bytes = %02X %02X, want 00 1A from one latch", high, low)
+	}
+}
+
+func TestRTCMillis(t *testing.T) {
+	r := CreateFixedRTCDevice(rtcEpoch)
+	r.Advance(70000 * time.Millisecond)
+	millis := uint32(r.GetUint16(rtcMillisHi))<<16 | uint32(r.GetUint16(rtcMillisLo))
+	if millis != 70000 {
+		t.Errorf("millis = %d, want 70000", millis)
+	}
+
+	r.Advance(time.Second)
+	high := r.GetUint8(rtcMillisHi)
+	r.Advance(time.Minute)
+	low := r.GetUint8(rtcMillisHi + 1)
+	if got := uint16(high)<<8 | uint16(low); got != 1 {
+		t.Errorf("MILLIS_HI bytes = %04X, want 0001 from one read", got)
+	}
+	if lo := r.GetUint16(rtcMillisLo); lo != 71000&0xFFFF {
+		t.Errorf("MILLIS_LO = %d, want the %d latched with MILLIS_HI", lo, 71000&0xFFFF)
+	}
+
+	r.SetTime(rtcEpoch.Add(-time.Hour))
+	if hi, lo := r.GetUint16(rtcMillisHi), r.GetUint16(rtcMillisLo); hi != 0 || lo != 0 {
+		t.Errorf("millis before the creation time = %04X%04X, want 0", hi, lo)
+	}
+}
@@ -0,0 +1,248 @@
+package devices
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	memorymapper "github.com/martbul/memoryMapper"
+)
+
+//INFO: Semihosting port. Lets guest code use host files and the host console without a driver.
+// The guest writes the address of a parameter block to PARAM and the operation number to CALL,
+// the call runs immediately and RESULT/ERROR are valid on the next instruction.
+// All paths are resolved inside the configured host directory, nothing outside of it can be opened.
+//
+// Register layout (16 bit registers, offsets relative to the mapped start):
+//
+//	+0x00 PARAM   guest address of the parameter block
+//	+0x02 CALL    writing an operation number runs it
+//	+0x04 RESULT  handle or byte count returned by the last call
+//	+0x06 ERROR   0 when the last call succeeded, 1 otherwise
+//
+// Operations and their parameter blocks (16 bit words):
+//
+//	1 OPEN   { path address, path length, mode }  mode 0 read, 1 write (create/truncate), 2 append. RESULT = handle
+//	2 CLOSE  { handle }
+//	3 READ   { handle, buffer address, length }   RESULT = bytes read (0 at end of file)
+//	4 WRITE  { handle, buffer address, length }   RESULT = bytes written. Handle 0 is the host console
+//	5 PRINT  { buffer address, length }           writes to the host console
+
+const (
+	SemihostOpen  = 1
+	SemihostClose = 2
+	SemihostRead  = 3
+	SemihostWrite = 4
+	SemihostPrint = 5
+
+	SemihostModeRead   = 0
+	SemihostModeWrite  = 1
+	SemihostModeAppend = 2
+
+	SemihostSize = 0x08
+
+	semihostParam  = 0x00
+	semihostCall   = 0x02
+	semihostResult = 0x04
+	semihostError  = 0x06
+)
+
+// SemihostDevice gives the guest sandboxed access to host files and the console
+type SemihostDevice struct {
+	memory     *memorymapper.MemoryMapper
+	root       *os.Root
+	console    io.Writer
+	files      map[uint16]*os.File
+	nextHandle uint16
+
+	param  uint16
+	result uint16
+	err    uint16
+	latch  registerLatch
+}
+
+// CreateSemihostDevice sandboxes file access to dir, console output goes to console (os.Stdout when nil)
+func CreateSemihostDevice(memory *memorymapper.MemoryMapper, dir string, console io.Writer) (*SemihostDevice, error) {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, fmt.Errorf("semihost: %w", err)
+	}
+	if console == nil {
+		console = os.Stdout
+	}
+	return &SemihostDevice{
+		memory:     memory,
+		root:       root,
+		console:    console,
+		files:      make(map[uint16]*os.File),
+		nextHandle: 1,
+	}, nil
+}
+
+// Close closes every file the guest left open and releases the sandbox directory
+func (s *SemihostDevice) Close() error {
+	for handle, file := range s.files {
+		file.Close()
+		delete(s.files, handle)
+	}
+	return s.root.Close()
+}
+
+// Reset closes the files the guest left open
+func (s *SemihostDevice) Reset() {
+	for handle, file := range s.files {
+		file.Close()
+		delete(s.files, handle)
+	}
+	s.nextHandle = 1
+	s.param, s.result, s.err = 0, 0, 0
+	s.latch = registerLatch{}
+}
+
+func (s *SemihostDevice) GetUint16(address int) uint16 {
+	switch address &^ 1 {
+	case semihostParam:
+		return s.param
+	case semihostResult:
+		return s.result
+	case semihostError:
+		return s.err
+	}
+	return 0
+}
+
+func (s *SemihostDevice) SetUint16(address int, value uint16) {
+	switch address &^ 1 {
+	case semihostParam:
+		s.param = value
+	case semihostCall:
+		result, err := s.call(value)
+		s.result = result
+		s.err = 0
+		if err != nil {
+			s.err = 1
+		}
+	}
+}
+
+func (s *SemihostDevice) GetUint8(address int) uint8 {
+	return registerByte(s, address)
+}
+
+func (s *SemihostDevice) SetUint8(address int, value uint8) {
+	if address&^1 == semihostCall {
+		s.latch.writeByte(s, address, value) // the operation runs once the low byte is written
+		return
+	}
+	setRegisterByte(s, address, value)
+}
+
+func (s *SemihostDevice) call(operation uint16) (uint16, error) {
+	switch operation {
+	case SemihostOpen:
+		path, err := s.readBytes(s.arg(0), s.arg(1))
+		if err != nil {
+			return 0, err
+		}
+		return s.open(string(path), s.arg(2))
+
+	case SemihostClose:
+		file, ok := s.files[s.arg(0)]
+		if !ok {
+			return 0, fmt.Errorf("bad handle")
+		}
+		delete(s.files, s.arg(0))
+		return 0, file.Close()
+
+	case SemihostRead:
+		file, ok := s.files[s.arg(0)]
+		if !ok {
+			return 0, fmt.Errorf("bad handle")
+		}
+		data := make([]byte, s.arg(2))
+		n, err := file.Read(data)
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		return uint16(n), s.writeBytes(s.arg(1), data[:n])
+
+	case SemihostWrite:
+		data, err := s.readBytes(s.arg(1), s.arg(2))
+		if err != nil {
+			return 0, err
+		}
+		if s.arg(0) == 0 {
+			n, err := s.console.Write(data)
+			return uint16(n), err
+		}
+		file, ok := s.files[s.arg(0)]
+		if !ok {
+			return 0, fmt.Errorf("bad handle")
+		}
+		n, err := file.Write(data)
+		return uint16(n), err
+
+	case SemihostPrint:
+		data, err := s.readBytes(s.arg(0), s.arg(1))
+		if err != nil {
+			return 0, err
+		}
+		n, err := s.console.Write(data)
+		return uint16(n), err
+	}
+	return 0, fmt.Errorf("unknown operation %d", operation)
+}
+
+func (s *SemihostDevice) open(path string, mode uint16) (uint16, error) {
+	var flag int
+	switch mode {
+	case SemihostModeRead:
+		flag = os.O_RDONLY
+	case SemihostModeWrite:
+		flag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	case SemihostModeAppend:
+		flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	default:
+		return 0, fmt.Errorf("unknown mode %d", mode)
+	}
+
+	file, err := s.root.OpenFile(path, flag, 0644)
+	if err != nil {
+		return 0, err
+	}
+	//INFO: handle 0 is reserved for the console, skip it when the counter wraps around
+	for s.nextHandle == 0 || s.files[s.nextHandle] != nil {
+		s.nextHandle++
+	}
+	handle := s.nextHandle
+	s.nextHandle++
+	s.files[handle] = file
+	return handle, nil
+}
+
+// arg reads the n-th word of the parameter block
+func (s *SemihostDevice) arg(n int) uint16 {
+	value, _ := s.memory.GetUint16(int(s.param) + n*2)
+	return value
+}
+
+func (s *SemihostDevice) readBytes(address, length uint16) ([]byte, error) {
+	data := make([]byte, length)
+	for i := range data {
+		value, err := s.memory.GetUint8(int(address) + i)
+		if err != nil {
+			return nil, err
+		}
+		data[i] = value
+	}
+	return data, nil
+}
+
+func (s *SemihostDevice) writeBytes(address uint16, data []byte) error {
+	for i, value := range data {
+		if err := s.memory.SetUint8(int(address)+i, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
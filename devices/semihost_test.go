@@ -0,0 +1,150 @@
+package devices
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/martbul/memory"
+	memorymapper "github.com/martbul/memoryMapper"
+)
+
+// semihostTest is a semihost device over 64k of ram sandboxed to a
+// temporary directory
+type semihostTest struct {
+	*SemihostDevice
+	ram     []byte
+	dir     string
+	console bytes.Buffer
+}
+
+func newSemihostTest(t *testing.T) *semihostTest {
+	t.Helper()
+	ram := memory.CreateMemory(0x10000)
+	mapper := memorymapper.NewMemoryMapper()
+	mapper.Map(ram, 0, 0xffff)
+	test := &semihostTest{ram: ram.GetBuffer(), dir: t.TempDir()}
+	s, err := CreateSemihostDevice(mapper, test.dir, &test.console)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	test.SemihostDevice = s
+	return test
+}
+
+// run writes the parameter block to 0x1000 and calls the operation, it
+// returns RESULT and whether ERROR is clear
+func (s *semihostTest) run(operation uint16, params ...uint16) (uint16, bool) {
+	for i, param := range params {
+		s.ram[0x1000+i*2], s.ram[0x1000+i*2+1] = byte(param>>8), byte(param)
+	}
+	s.SetUint16(semihostParam, 0x1000)
+	s.SetUint16(semihostCall, operation)
+	return s.GetUint16(semihostResult), s.GetUint16(semihostError) == 0
+}
+
+// text places text in ram at address and returns the address and length
+func (s *semihostTest) text(address uint16, text string) (uint16, uint16) {
+	copy(s.ram[address:], text)
+	return address, uint16(len(text))
+}
+
+func TestSemihostFiles(t *testing.T) {
+	s := newSemihostTest(t)
+	path, pathLength := s.text(0x2000, "out.txt")
+	data, dataLength := s.text(0x3000, "hello")
+
+	handle, ok := s.run(SemihostOpen, path, pathLength, SemihostModeWrite)
+	if !ok || handle == 0 {
+		t.Fatalf("OPEN for writing = %d, %v", handle, ok)
+	}
+	if n, ok := s.run(SemihostWrite, handle, data, dataLength); !ok || n != 5 {
+		t.Errorf("WRITE = %d, %v, want 5 bytes", n, ok)
+	}
+	if _, ok := s.run(SemihostClose, handle); !ok {
+		t.Error("CLOSE failed")
+	}
+	if written, err := os.ReadFile(filepath.Join(s.dir, "out.txt")); err != nil || string(written) != "hello" {
+		t.Errorf("out.txt = %q, %v, want \"hello\"", written, err)
+	}
+
+	handle, ok = s.run(SemihostOpen, path, pathLength, SemihostModeRead)
+	if !ok {
+		t.Fatal("OPEN for reading failed")
+	}
+	if n, ok := s.run(SemihostRead, handle, 0x4000, 0x10); !ok || n != 5 || string(s.ram[0x4000:0x4005]) != "hello" {
+		t.Errorf("READ = %d, %v, ram %q, want 5 bytes of \"hello\"", n, ok, s.ram[0x4000:0x4005])
+	}
+	if n, ok := s.run(SemihostRead, handle, 0x4000, 0x10); !ok || n != 0 {
+		t.Errorf("READ at the end of the file = %d, %v, want 0", n, ok)
+	}
+}
+
+func TestSemihostErrors(t *testing.T) {
+	s := newSemihostTest(t)
+	outside, outsideLength := s.text(0x2000, "../outside.txt")
+	missing, missingLength := s.text(0x2100, "missing.txt")
+
+	tests := []struct {
+		name      string
+		operation uint16
+		params    []uint16
+	}{
+		{"path outside the directory", SemihostOpen, []uint16{outside, outsideLength, SemihostModeWrite}},
+		{"missing file", SemihostOpen, []uint16{missing, missingLength, SemihostModeRead}},
+		{"unknown mode", SemihostOpen, []uint16{missing, missingLength, 7}},
+		{"bad handle", SemihostRead, []uint16{9, 0x4000, 1}},
+		{"console is not open", SemihostClose, []uint16{0}},
+		{"unknown operation", 0x0100, nil},
+	}
+	for _, test := range tests {
+		if result, ok := s.run(test.operation, test.params...); ok || result != 0 {
+			t.Errorf("%s: RESULT = %d, ERROR clear = %v, want an error", test.name, result, ok)
+		}
+	}
+	text, textLength := s.text(0x3000, "ok")
+	if _, succeeded := s.run(SemihostPrint, text, textLength); !succeeded {
+		t.Error("ERROR is still set after a call that succeeded")
+	}
+}
+
+func TestSemihostCallByteWrite(t *testing.T) {
+	s := newSemihostTest(t)
+	address, length := s.text(0x3000, "once\n")
+	s.ram[0x1000], s.ram[0x1001] = byte(address>>8), byte(address)
+	s.ram[0x1002], s.ram[0x1003] = 0, byte(length)
+	s.SetUint8(semihostParam, 0x10)
+	s.SetUint8(semihostParam+1, 0x00)
+
+	s.SetUint8(semihostCall, 0)
+	if s.console.Len() != 0 || s.GetUint16(semihostError) != 0 {
+		t.Fatalf("the high byte of CALL ran an operation, console %q", s.console.String())
+	}
+	s.SetUint8(semihostCall+1, SemihostPrint)
+	if got := s.console.String(); got != "once\n" {
+		t.Errorf("console = %q, want one print", got)
+	}
+	if high, low := s.GetUint8(semihostResult), s.GetUint8(semihostResult+1); high != 0 || low != 5 {
+		t.Errorf("RESULT bytes = %02X %02X, want 00 05", high, low)
+	}
+}
+
+func TestSemihostReset(t *testing.T) {
+	s := newSemihostTest(t)
+	path, pathLength := s.text(0x2000, "log.txt")
+	handle, ok := s.run(SemihostOpen, path, pathLength, SemihostModeWrite)
+	if !ok {
+		t.Fatal("OPEN failed")
+	}
+
+	s.Reset()
+	data, dataLength := s.text(0x3000, "x")
+	if _, ok := s.run(SemihostWrite, handle, data, dataLength); ok {
+		t.Error("the handle is still open after Reset")
+	}
+	if again, ok := s.run(SemihostOpen, path, pathLength, SemihostModeAppend); !ok || again != handle {
+		t.Errorf("OPEN after Reset = %d, %v, want handle %d again", again, ok, handle)
+	}
+}
@@ -0,0 +1,231 @@
+package devices
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+//INFO: Programmable sound generator. Three square wave channels and one noise channel.
+// There is no audio output, the samples are rendered into an in-memory pcm buffer (8 bit, mono) that can be saved as a wav file.
+// Rendering follows the cpu cycle counter: every Tick produces cycles*sampleRate/clockRate samples, so a run always sounds the same.
+//
+// Register layout (16 bit registers, offsets relative to the mapped start), channel n lives at n*8:
+//
+//	+0 FREQ      frequency in Hz (for the noise channel: the rate the noise value changes)
+//	+2 VOLUME    0 - 15
+//	+4 ENVELOPE  low byte: cycles (in units of 256) between two volume steps, 0 = no envelope. bit8: 1 = rise, 0 = decay
+//	+6 CTRL      bit0 enable. Enabling a channel restarts its envelope
+//
+// Channels 0 - 2 are square waves, channel 3 is noise.
+
+const (
+	SoundChannels     = 4
+	SoundChannelSize  = 8
+	SoundSize         = SoundChannels * SoundChannelSize
+	SoundNoiseChannel = 3
+
+	SoundCtrlEnable  = 1 << 0
+	SoundEnvelopeUp  = 1 << 8
+	SoundMaxVolume   = 15
+	soundEnvelopeDiv = 256
+
+	soundFreq     = 0
+	soundVolume   = 2
+	soundEnvelope = 4
+	soundCtrl     = 6
+)
+
+type soundChannel struct {
+	freq     uint16
+	volume   uint16
+	envelope uint16
+	ctrl     uint16
+
+	phase        float64 // position in the current period, 0 - 1
+	noise        uint16  // lfsr state of the noise channel
+	envelopeTick int     // cycles counted towards the next envelope step
+}
+
+// SoundDevice renders its channels into a pcm buffer
+type SoundDevice struct {
+	channels   [SoundChannels]soundChannel
+	sampleRate int
+	clockRate  int
+	cycleCarry int // cycles*sampleRate left over from the last Tick
+	samples    []byte
+}
+
+// CreateSoundDevice creates a generator producing sampleRate samples per second,
+// assuming the cpu executes clockRate instructions per second. Both have to be positive
+// and sampleRate has to fit in the 32 bits of the wav header
+func CreateSoundDevice(sampleRate, clockRate int) (*SoundDevice, error) {
+	if sampleRate <= 0 || sampleRate > math.MaxUint32 || clockRate <= 0 {
+		return nil, fmt.Errorf("sound: invalid sampleRate %d or clockRate %d, both must be positive", sampleRate, clockRate)
+	}
+	s := &SoundDevice{sampleRate: sampleRate, clockRate: clockRate}
+	s.channels[SoundNoiseChannel].noise = 0xACE1
+	return s, nil
+}
+
+func (s *SoundDevice) GetUint16(address int) uint16 {
+	if address >= SoundSize {
+		return 0
+	}
+	channel := &s.channels[address/SoundChannelSize]
+
+	switch address % SoundChannelSize &^ 1 {
+	case soundFreq:
+		return channel.freq
+	case soundVolume:
+		return channel.volume
+	case soundEnvelope:
+		return channel.envelope
+	case soundCtrl:
+		return channel.ctrl
+	}
+	return 0
+}
+
+func (s *SoundDevice) SetUint16(address int, value uint16) {
+	if address >= SoundSize {
+		return
+	}
+	channel := &s.channels[address/SoundChannelSize]
+
+	switch address % SoundChannelSize &^ 1 {
+	case soundFreq:
+		channel.freq = value
+	case soundVolume:
+		channel.volume = min(value, SoundMaxVolume)
+	case soundEnvelope:
+		channel.envelope = value
+	case soundCtrl:
+		if channel.ctrl&SoundCtrlEnable == 0 && value&SoundCtrlEnable != 0 {
+			channel.envelopeTick = 0
+		}
+		channel.ctrl = value
+	}
+}
+
+func (s *SoundDevice) GetUint8(address int) uint8 {
+	return registerByte(s, address)
+}
+
+func (s *SoundDevice) SetUint8(address int, value uint8) {
+	setRegisterByte(s, address, value)
+}
+
+// Reset silences every channel, the samples rendered so far are kept
+func (s *SoundDevice) Reset() {
+	s.channels = [SoundChannels]soundChannel{}
+	s.channels[SoundNoiseChannel].noise = 0xACE1
+}
+
+// Tick renders the samples that belong to the given number of cpu cycles
+func (s *SoundDevice) Tick(cycles int) {
+	for i := range s.channels {
+		s.stepEnvelope(&s.channels[i], cycles)
+	}
+
+	s.cycleCarry += cycles * s.sampleRate
+	for s.cycleCarry >= s.clockRate {
+		s.cycleCarry -= s.clockRate
+		s.samples = append(s.samples, s.mix())
+	}
+}
+
+func (s *SoundDevice) stepEnvelope(channel *soundChannel, cycles int) {
+	period := int(channel.envelope&0xFF) * soundEnvelopeDiv
+	if channel.ctrl&SoundCtrlEnable == 0 || period == 0 {
+		return
+	}
+
+	channel.envelopeTick += cycles
+	for channel.envelopeTick >= period {
+		channel.envelopeTick -= period
+		if channel.envelope&SoundEnvelopeUp != 0 && channel.volume < SoundMaxVolume {
+			channel.volume++
+		} else if channel.envelope&SoundEnvelopeUp == 0 && channel.volume > 0 {
+			channel.volume--
+		}
+	}
+}
+
+// mix produces the next unsigned 8 bit sample of all channels
+func (s *SoundDevice) mix() byte {
+	sum := 0
+	for i := range s.channels {
+		channel := &s.channels[i]
+		if channel.ctrl&SoundCtrlEnable == 0 || channel.freq == 0 {
+			continue
+		}
+
+		step := float64(channel.freq) / float64(s.sampleRate)
+		channel.phase += step
+
+		high := channel.phase < 0.5
+		if i == SoundNoiseChannel {
+			for channel.phase >= 1 {
+				// 16 bit galois lfsr
+				lsb := channel.noise & 1
+				channel.noise >>= 1
+				if lsb != 0 {
+					channel.noise ^= 0xB400
+				}
+				channel.phase--
+			}
+			high = channel.noise&1 != 0
+		}
+		for channel.phase >= 1 {
+			channel.phase--
+		}
+
+		//INFO: every channel swings +-volume*2 around the center, 4 channels at full volume still fit in a byte
+		amplitude := int(channel.volume) * 2
+		if high {
+			sum += amplitude
+		} else {
+			sum -= amplitude
+		}
+	}
+	return byte(128 + sum)
+}
+
+// Samples returns the rendered 8 bit unsigned mono pcm data
+func (s *SoundDevice) Samples() []byte {
+	return s.samples
+}
+
+// WriteWAV writes the rendered samples as a wav file
+func (s *SoundDevice) WriteWAV(w io.Writer) error {
+	dataSize := uint32(len(s.samples))
+	header := []any{
+		[]byte("RIFF"), 36 + dataSize, []byte("WAVE"),
+		[]byte("fmt "), uint32(16), uint16(1), uint16(1), // pcm, mono
+		uint32(s.sampleRate), uint32(s.sampleRate), uint16(1), uint16(8),
+		[]byte("data"), dataSize,
+	}
+	for _, field := range header {
+		if err := binary.Write(w, binary.LittleEndian, field); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write(s.samples)
+	return err
+}
+
+// SaveWAV writes the rendered samples to a wav file at path
+func (s *SoundDevice) SaveWAV(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := s.WriteWAV(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
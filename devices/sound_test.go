@@ -0,0 +1,90 @@
+package devices
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestSoundRates(t *testing.T) {
+	for _, rates := range [][2]int{{0, 1000}, {-8000, 1000}, {8000, 0}, {8000, -1}} {
+		if _, err := CreateSoundDevice(rates[0], rates[1]); err == nil {
+			t.Errorf("CreateSoundDevice(%d, %d) did not fail", rates[0], rates[1])
+		}
+	}
+}
+
+func TestSoundSamplesFollowCycles(t *testing.T) {
+	s, err := CreateSoundDevice(8000, 1000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		s.Tick(1000)
+	}
+	if n := len(s.Samples()); n != 8000 {
+		t.Errorf("one second of cycles rendered %d samples, want 8000", n)
+	}
+}
+
+func TestSoundRegisters(t *testing.T) {
+	s, _ := CreateSoundDevice(8000, 8000)
+	channel := 1 * SoundChannelSize
+
+	s.SetUint16(channel+soundVolume, 99)
+	if volume := s.GetUint16(channel + soundVolume); volume != SoundMaxVolume {
+		t.Errorf("VOLUME = %d, want it clamped to %d", volume, SoundMaxVolume)
+	}
+
+	s.SetUint8(channel+soundFreq, 0x01)
+	s.SetUint8(channel+soundFreq+1, 0xB8)
+	if freq := s.GetUint16(channel + soundFreq); freq != 440 {
+		t.Errorf("FREQ written as bytes = %d, want 440", freq)
+	}
+	if high := s.GetUint8(channel + soundFreq); high != 0x01 {
+		t.Errorf("FREQ high byte = %02X, want 01", high)
+	}
+}
+
+func TestSoundEnvelope(t *testing.T) {
+	s, _ := CreateSoundDevice(8000, 8000)
+	s.SetUint16(soundVolume, SoundMaxVolume)
+	s.SetUint16(soundEnvelope, 1) // one step every 256 cycles
+	s.SetUint16(soundCtrl, SoundCtrlEnable)
+	s.Tick(256 * 5)
+	if volume := s.GetUint16(soundVolume); volume != SoundMaxVolume-5 {
+		t.Errorf("VOLUME after 5 decay steps = %d, want %d", volume, SoundMaxVolume-5)
+	}
+
+	s.SetUint16(soundEnvelope, 1|SoundEnvelopeUp)
+	s.Tick(256 * 100)
+	if volume := s.GetUint16(soundVolume); volume != SoundMaxVolume {
+		t.Errorf("VOLUME after rising = %d, want %d", volume, SoundMaxVolume)
+	}
+}
+
+func TestSoundWAV(t *testing.T) {
+	s, _ := CreateSoundDevice(8000, 8000)
+	s.SetUint16(soundFreq, 1000)
+	s.SetUint16(soundVolume, SoundMaxVolume)
+	s.SetUint16(soundCtrl, SoundCtrlEnable)
+	s.Tick(80)
+
+	var buffer bytes.Buffer
+	if err := s.WriteWAV(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	wav := buffer.Bytes()
+	if len(wav) != 44+80 || string(wav[0:4]) != "RIFF" || string(wav[8:12]) != "WAVE" {
+		t.Fatalf("bad wav header % X", wav[:min(len(wav), 44)])
+	}
+	if rate := binary.LittleEndian.Uint32(wav[24:28]); rate != 8000 {
+		t.Errorf("sample rate in the header = %d, want 8000", rate)
+	}
+	if size := binary.LittleEndian.Uint32(wav[40:44]); size != 80 {
+		t.Errorf("data size = %d, want 80", size)
+	}
+	if wav[44] == 128 {
+		t.Error("an enabled channel rendered silence")
+	}
+}
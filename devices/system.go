@@ -0,0 +1,139 @@
+package devices
+
+import "github.com/martbul/memory"
+
+//INFO: System controller. Lets the guest reset the machine, power it off with an exit status and guards it with a watchdog.
+//
+// Register layout (16 bit registers, offsets relative to the mapped start):
+//
+//	+0x00 RESET       writing 1 resets the cpu, 3 resets the cpu and clears ram
+//	+0x02 POWEROFF    writing a value stops the machine, the value is the exit status seen by go (cpu.ExitStatus)
+//	+0x04 WD_CTRL     bit0 watchdog enable, bit1 action on timeout (0 reset, 1 fault = halt the cpu)
+//	+0x06 WD_TIMEOUT  timeout in units of 16 cycles
+//	+0x08 WD_KICK     writing any value restarts the watchdog countdown
+//	+0x0A RESET_CAUSE 0 power on, 1 software reset, 2 watchdog reset (survives the reset)
+
+const (
+	SystemSize = 0x0C
+
+	SystemResetCPU = 1 << 0
+	SystemResetRAM = 1 << 1
+
+	WatchdogCtrlEnable = 1 << 0
+	WatchdogCtrlFault  = 1 << 1
+	WatchdogUnit       = 16
+
+	ResetCausePowerOn  = 0
+	ResetCauseSoftware = 1
+	ResetCauseWatchdog = 2
+
+	systemReset      = 0x00
+	systemPowerOff   = 0x02
+	systemWdCtrl     = 0x04
+	systemWdTimeout  = 0x06
+	systemWdKick     = 0x08
+	systemResetCause = 0x0A
+)
+
+// Machine is the part of the cpu the system controller drives
+type Machine interface {
+	Reset()
+	PowerOff(status uint16)
+	Halt(reason string)
+}
+
+// SystemDevice is the power, reset and watchdog controller
+type SystemDevice struct {
+	machine Machine
+	ram     *memory.DataView
+
+	wdCtrl     uint16
+	wdTimeout  uint16
+	wdElapsed  int
+	resetCause uint16
+	latch      registerLatch
+}
+
+// CreateSystemDevice creates the controller for machine, ram is cleared by a full reset and may be nil
+func CreateSystemDevice(machine Machine, ram *memory.DataView) *SystemDevice {
+	return &SystemDevice{machine: machine, ram: ram}
+}
+
+// Reset disables the watchdog, the guest has to enable it again after every reset
+func (s *SystemDevice) Reset() {
+	s.wdCtrl = 0
+	s.wdElapsed = 0
+	s.latch = registerLatch{}
+}
+
+func (s *SystemDevice) GetUint16(address int) uint16 {
+	switch address &^ 1 {
+	case systemWdCtrl:
+		return s.wdCtrl
+	case systemWdTimeout:
+		return s.wdTimeout
+	case systemResetCause:
+		return s.resetCause
+	}
+	return 0
+}
+
+func (s *SystemDevice) SetUint16(address int, value uint16) {
+	switch address &^ 1 {
+	case systemReset:
+		if value&SystemResetCPU != 0 {
+			s.reset(ResetCauseSoftware, value&SystemResetRAM != 0)
+		}
+	case systemPowerOff:
+		s.machine.PowerOff(value)
+	case systemWdCtrl:
+		s.wdCtrl = value
+		s.wdElapsed = 0
+	case systemWdTimeout:
+		s.wdTimeout = value
+		s.wdElapsed = 0
+	case systemWdKick:
+		s.wdElapsed = 0
+	}
+}
+
+func (s *SystemDevice) GetUint8(address int) uint8 {
+	return registerByte(s, address)
+}
+
+func (s *SystemDevice) SetUint8(address int, value uint8) {
+	switch address &^ 1 {
+	case systemReset, systemPowerOff, systemWdKick:
+		s.latch.writeByte(s, address, value) // act once, with the whole value
+	default:
+		setRegisterByte(s, address, value)
+	}
+}
+
+// Tick counts down the watchdog
+func (s *SystemDevice) Tick(cycles int) {
+	if s.wdCtrl&WatchdogCtrlEnable == 0 {
+		return
+	}
+
+	s.wdElapsed += cycles
+	if s.wdElapsed < int(s.wdTimeout)*WatchdogUnit {
+		return
+	}
+
+	if s.wdCtrl&WatchdogCtrlFault != 0 {
+		s.wdCtrl &^= WatchdogCtrlEnable
+		s.machine.Halt("watchdog timeout")
+		return
+	}
+	s.reset(ResetCauseWatchdog, false)
+}
+
+func (s *SystemDevice) reset(cause uint16, clearRAM bool) {
+	s.machine.Reset()
+	s.Reset() //INFO: machine.Reset already resets mapped devices, this covers a controller that is not mapped
+	if clearRAM && s.ram != nil {
+		clear(s.ram.GetBuffer())
+	}
+	s.resetCause = cause
+}
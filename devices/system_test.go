@@ -0,0 +1,125 @@
+package devices
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/martbul/memory"
+)
+
+// testMachine records what the system controller asks of the cpu
+type testMachine struct {
+	events []string
+	status uint16
+}
+
+func (m *testMachine) Reset() { m.events = append(m.events, "reset") }
+func (m *testMachine) PowerOff(status uint16) {
+	m.events, m.status = append(m.events, "power off"), status
+}
+func (m *testMachine) Halt(reason string) { m.events = append(m.events, reason) }
+
+func TestSystemControl(t *testing.T) {
+	tests := []struct {
+		name     string
+		address  int
+		value    uint16
+		events   []string
+		cause    uint16
+		ramClear bool
+	}{
+		{"reset", systemReset, SystemResetCPU, []string{"reset"}, ResetCauseSoftware, false},
+		{"reset and clear ram", systemReset, SystemResetCPU | SystemResetRAM, []string{"reset"}, ResetCauseSoftware, true},
+		{"clear ram alone does nothing", systemReset, SystemResetRAM, nil, ResetCausePowerOn, false},
+		{"power off", systemPowerOff, 0x0102, []string{"power off"}, ResetCausePowerOn, false},
+	}
+	for _, test := range tests {
+		for _, bytes := range []bool{false, true} {
+			machine := &testMachine{}
+			ram := memory.CreateMemory(0x10)
+			ram.GetBuffer()[3] = 0xAA
+			s := CreateSystemDevice(machine, ram)
+			if bytes {
+				s.SetUint8(test.address, uint8(test.value>>8))
+				s.SetUint8(test.address+1, uint8(test.value))
+			} else {
+				s.SetUint16(test.address, test.value)
+			}
+
+			if !slices.Equal(machine.events, test.events) {
+				t.Errorf("%s (bytes %v): events = %v, want %v", test.name, bytes, machine.events, test.events)
+			}
+			if test.address == systemPowerOff && machine.status != test.value {
+				t.Errorf("%s (bytes %v): exit status = %04X, want %04X", test.name, bytes, machine.status, test.value)
+			}
+			if cause := s.GetUint16(systemResetCause); cause != test.cause {
+				t.Errorf("%s (bytes %v): RESET_CAUSE = %d, want %d", test.name, bytes, cause, test.cause)
+			}
+			if cleared := ram.GetBuffer()[3] == 0; cleared != test.ramClear {
+				t.Errorf("%s (bytes %v): ram cleared = %v, want %v", test.name, bytes, cleared, test.ramClear)
+			}
+		}
+	}
+}
+
+func TestSystemWatchdog(t *testing.T) {
+	tests := []struct {
+		name   string
+		ctrl   uint16
+		kicks  bool
+		events []string
+		cause  uint16
+	}{
+		{"reset", WatchdogCtrlEnable, false, []string{"reset"}, ResetCauseWatchdog},
+		{"fault", WatchdogCtrlEnable | WatchdogCtrlFault, false, []string{"watchdog timeout"}, ResetCausePowerOn},
+		{"kicked", WatchdogCtrlEnable, true, nil, ResetCausePowerOn},
+		{"disabled", 0, false, nil, ResetCausePowerOn},
+	}
+	for _, test := range tests {
+		machine := &testMachine{}
+		s := CreateSystemDevice(machine, nil)
+		s.SetUint16(systemWdTimeout, 4) // 64 cycles
+		s.SetUint16(systemWdCtrl, test.ctrl)
+		for i := 0; i < 10; i++ {
+			s.Tick(10)
+			if test.kicks {
+				s.SetUint8(systemWdKick, 0)
+				s.SetUint8(systemWdKick+1, 0)
+			}
+		}
+
+		if !slices.Equal(machine.events, test.events) {
+			t.Errorf("%s: events = %v, want %v", test.name, machine.events, test.events)
+		}
+		if cause := s.GetUint16(systemResetCause); cause != test.cause {
+			t.Errorf("%s: RESET_CAUSE = %d, want %d", test.name, cause, test.cause)
+		}
+		if ctrl := s.GetUint16(systemWdCtrl); ctrl&WatchdogCtrlEnable != 0 && test.events != nil {
+			t.Errorf("%s: the watchdog is still enabled after it fired", test.name)
+		}
+	}
+}
+
+func TestSystemWatchdogTiming(t *testing.T) {
+	machine := &testMachine{}
+	s := CreateSystemDevice(machine, nil)
+	s.SetUint16(systemWdTimeout, 2)
+	s.SetUint16(systemWdCtrl, WatchdogCtrlEnable)
+	s.Tick(2*WatchdogUnit - 1)
+	if len(machine.events) != 0 {
+		t.Fatalf("the watchdog fired after %d cycles", 2*WatchdogUnit-1)
+	}
+	s.Tick(1)
+	if !slices.Equal(machine.events, []string{"reset"}) {
+		t.Errorf("events = %v after %d cycles, want a reset", machine.events, 2*WatchdogUnit)
+	}
+
+	s.SetUint16(systemWdCtrl, WatchdogCtrlEnable)
+	s.Tick(WatchdogUnit)
+	s.SetUint8(systemWdCtrl, 0)
+	s.SetUint8(systemWdCtrl+1, WatchdogCtrlEnable)
+	s.Tick(2*WatchdogUnit - 1)
+	if len(machine.events) != 1 {
+		t.Errorf("events = %v, want the countdown restarted by writing CTRL", machine.events)
+	}
+}
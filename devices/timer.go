@@ -0,0 +1,168 @@
+package devices
+
+//INFO: Programmable interval timer.
+// The timer is clocked by the cpu's cycle counter (one cycle per executed instruction), so a run is always deterministic.
+//
+// Register layout (offsets relative to the mapped start, every register is 16 bits wide):
+//
+//	channel n lives at n*8:
+//	  +0 CTRL      bit0 enable, bit1 periodic (0 = one-shot), bit2 interupt enable
+//	  +2 RELOAD    value loaded into COUNT when the channel is enabled or (periodic mode) expires
+//	  +4 COUNT     current count, counts down to 0. Writing sets it directly
+//	  +6 PRESCALER COUNT is decremented once every PRESCALER+1 cycles
+//	STATUS at channels*8: bit n is set when channel n expired, writing a 1 clears the bit
+//
+// Channel n raises the interupt firstInterupt+n on expiry when bit2 of its CTRL is set.
+
+const (
+	TimerCtrlEnable    = 1 << 0
+	TimerCtrlPeriodic  = 1 << 1
+	TimerCtrlInterrupt = 1 << 2
+
+	TimerChannelSize = 8
+
+	timerCtrl      = 0
+	timerReload    = 2
+	timerCount     = 4
+	timerPrescaler = 6
+)
+
+type timerChannel struct {
+	ctrl      uint16
+	reload    uint16
+	count     uint16
+	prescaler uint16
+	divider   int // cycles counted towards the next decrement, an int so PRESCALER $FFFF doesn't wrap it
+}
+
+// TimerDevice is a multi channel countdown timer
+type TimerDevice struct {
+	channels      []timerChannel
+	status        uint16
+	interupt      func(uint16)
+	firstInterupt uint16
+	latch         registerLatch
+}
+
+// CreateTimerDevice creates a timer with n channels (at most 16).
+// interupt is called with firstInterupt+channel on expiry, usually cpu.RequestInterupt. It may be nil
+func CreateTimerDevice(n int, interupt func(uint16), firstInterupt uint16) *TimerDevice {
+	if n < 1 || n > 16 {
+		panic("timer: channel count must be between 1 and 16")
+	}
+	return &TimerDevice{
+		channels:      make([]timerChannel, n),
+		interupt:      interupt,
+		firstInterupt: firstInterupt,
+	}
+}
+
+// Size returns the number of bytes the device occupies in the address space
+func (t *TimerDevice) Size() int {
+	return len(t.channels)*TimerChannelSize + 2
+}
+
+func (t *TimerDevice) GetUint16(address int) uint16 {
+	index := address / TimerChannelSize
+	if index >= len(t.channels) {
+		return t.status
+	}
+	channel := &t.channels[index]
+
+	switch address % TimerChannelSize {
+	case timerCtrl:
+		return channel.ctrl
+	case timerReload:
+		return channel.reload
+	case timerCount:
+		return channel.count
+	case timerPrescaler:
+		return channel.prescaler
+	}
+	return 0
+}
+
+func (t *TimerDevice) SetUint16(address int, value uint16) {
+	index := address / TimerChannelSize
+	if index >= len(t.channels) {
+		t.status &^= value
+		return
+	}
+	channel := &t.channels[index]
+
+	switch address % TimerChannelSize {
+	case timerCtrl:
+		wasEnabled := channel.ctrl&TimerCtrlEnable != 0
+		channel.ctrl = value
+		if !wasEnabled && value&TimerCtrlEnable != 0 {
+			channel.count = channel.reload
+			channel.divider = 0
+		}
+	case timerReload:
+		channel.reload = value
+	case timerCount:
+		channel.count = value
+	case timerPrescaler:
+		channel.prescaler = value
+		channel.divider = 0
+	}
+}
+
+func (t *TimerDevice) GetUint8(address int) uint8 {
+	return registerByte(t, address)
+}
+
+func (t *TimerDevice) SetUint8(address int, value uint8) {
+	if address/TimerChannelSize >= len(t.channels) {
+		t.latch.writeByte(t, address, value) // STATUS, writing back what was read would clear it
+		return
+	}
+	setRegisterByte(t, address, value)
+}
+
+// Reset stops every channel and clears the registers
+func (t *TimerDevice) Reset() {
+	clear(t.channels)
+	t.status = 0
+	t.latch = registerLatch{}
+}
+
+// Tick advances every enabled channel by the given number of cpu cycles
+func (t *TimerDevice) Tick(cycles int) {
+	for i := range t.channels {
+		for c := 0; c < cycles; c++ {
+			t.tickChannel(i)
+		}
+	}
+}
+
+func (t *TimerDevice) tickChannel(index int) {
+	channel := &t.channels[index]
+	if channel.ctrl&TimerCtrlEnable == 0 {
+		return
+	}
+
+	channel.divider++
+	if channel.divider <= int(channel.prescaler) {
+		return
+	}
+	channel.divider = 0
+
+	if channel.count > 0 {
+		channel.count--
+	}
+	if channel.count != 0 {
+		return
+	}
+
+	// the channel expired
+	t.status |= 1 << index
+	if channel.ctrl&TimerCtrlPeriodic != 0 {
+		channel.count = channel.reload
+	} else {
+		channel.ctrl &^= TimerCtrlEnable
+	}
+	if channel.ctrl&TimerCtrlInterrupt != 0 && t.interupt != nil {
+		t.interupt(t.firstInterupt + uint16(index))
+	}
+}
@@ -0,0 +1,97 @@
+package devices
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTimerExpiry(t *testing.T) {
+	tests := []struct {
+		name      string
+		ctrl      uint16
+		reload    uint16
+		prescaler uint16
+		cycles    int
+		count     uint16 // COUNT after the cycles
+		enabled   bool
+		expired   bool // STATUS bit of the channel
+		raised    int  // interrupts
+	}{
+		{"one-shot", TimerCtrlEnable | TimerCtrlInterrupt, 3, 0, 10, 0, false, true, 1},
+		{"one-shot before expiry", TimerCtrlEnable | TimerCtrlInterrupt, 3, 0, 2, 1, true, false, 0},
+		{"periodic", TimerCtrlEnable | TimerCtrlPeriodic | TimerCtrlInterrupt, 3, 0, 10, 2, true, true, 3},
+		{"prescaler", TimerCtrlEnable | TimerCtrlPeriodic | TimerCtrlInterrupt, 2, 4, 10, 2, true, true, 1},
+		{"prescaler $FFFF", TimerCtrlEnable | TimerCtrlInterrupt, 2, 0xFFFF, 0x10000, 1, true, false, 0},
+		{"prescaler $FFFF expiry", TimerCtrlEnable | TimerCtrlInterrupt, 2, 0xFFFF, 0x20000, 0, false, true, 1},
+		{"no interrupt", TimerCtrlEnable | TimerCtrlPeriodic, 1, 0, 4, 1, true, true, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var raised []uint16
+			timer := CreateTimerDevice(2, func(n uint16) { raised = append(raised, n) }, 0x10)
+			channel := TimerChannelSize // channel 1
+			timer.SetUint16(channel+timerReload, test.reload)
+			timer.SetUint16(channel+timerPrescaler, test.prescaler)
+			timer.SetUint16(channel+timerCtrl, test.ctrl)
+			timer.Tick(test.cycles)
+
+			if count := timer.GetUint16(channel + timerCount); count != test.count {
+				t.Errorf("COUNT = %d, want %d", count, test.count)
+			}
+			if enabled := timer.GetUint16(channel+timerCtrl)&TimerCtrlEnable != 0; enabled != test.enabled {
+				t.Errorf("enabled = %v, want %v", enabled, test.enabled)
+			}
+			if expired := timer.GetUint16(timer.Size()-2)&0b10 != 0; expired != test.expired {
+				t.Errorf("STATUS bit 1 = %v, want %v", expired, test.expired)
+			}
+			if len(raised) != test.raised || slices.ContainsFunc(raised, func(n uint16) bool { return n != 0x11 }) {
+				t.Errorf("interrupts = %v, want %d of 0x11", raised, test.raised)
+			}
+		})
+	}
+}
+
+func TestTimerStatusByteWrite(t *testing.T) {
+	timer := CreateTimerDevice(16, nil, 0)
+	status := timer.Size() - 2
+	for _, channel := range []int{0, 8} {
+		timer.SetUint16(channel*TimerChannelSize+timerReload, 1)
+		timer.SetUint16(channel*TimerChannelSize+timerCtrl, TimerCtrlEnable)
+	}
+	timer.Tick(1)
+	if got := timer.GetUint16(status); got != 0x0101 {
+		t.Fatalf("STATUS = %04X, want 0101", got)
+	}
+
+	// a byte write clears only the bits written as 1, nothing is written back
+	timer.SetUint8(status+1, 0x01)
+	if got := timer.GetUint16(status); got != 0x0100 {
+		t.Errorf("STATUS after clearing bit 0 = %04X, want 0100", got)
+	}
+	timer.SetUint8(status, 0x01)
+	if got := timer.GetUint16(status); got != 0x0100 {
+		t.Errorf("STATUS after the high byte alone = %04X, want 0100 until the low byte is written", got)
+	}
+	timer.SetUint8(status+1, 0x00)
+	if got := timer.GetUint16(status); got != 0 {
+		t.Errorf("STATUS after clearing bit 8 = %04X, want 0", got)
+	}
+}
+
+func TestTimerByteAccess(t *testing.T) {
+	timer := CreateTimerDevice(1, nil, 0)
+	timer.SetUint8(timerReload, 0x12)
+	timer.SetUint8(timerReload+1, 0x34)
+	if reload := timer.GetUint16(timerReload); reload != 0x1234 {
+		t.Errorf("RELOAD = %04X, want 1234", reload)
+	}
+	timer.SetUint8(timerCtrl+1, TimerCtrlEnable)
+	if high, low := timer.GetUint8(timerCount), timer.GetUint8(timerCount+1); high != 0x12 || low != 0x34 {
+		t.Errorf("COUNT bytes = %02X %02X, want 12 34 loaded from RELOAD", high, low)
+	}
+
+	timer.Reset()
+	if ctrl, reload := timer.GetUint16(timerCtrl), timer.GetUint16(timerReload); ctrl != 0 || reload != 0 {
+		t.Errorf("CTRL, RELOAD = %04X, %04X after Reset, want 0", ctrl, reload)
+	}
+}
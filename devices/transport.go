@@ -0,0 +1,165 @@
+package devices
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"time"
+)
+
+//INFO: Host side transports for the nic.
+
+// PacketSwitch is an in-process switch, every frame sent on one port is delivered to all the other ports
+type PacketSwitch struct {
+	ports []*SwitchPort
+}
+
+func NewPacketSwitch() *PacketSwitch {
+	return &PacketSwitch{}
+}
+
+// Port creates a new port, use it as the transport of one nic
+func (s *PacketSwitch) Port() *SwitchPort {
+	port := &SwitchPort{sw: s}
+	s.ports = append(s.ports, port)
+	return port
+}
+
+// SwitchPort is one port of a PacketSwitch
+type SwitchPort struct {
+	sw     *PacketSwitch
+	queue  [][]byte
+	closed bool
+}
+
+func (p *SwitchPort) Send(frame []byte) error {
+	for _, port := range p.sw.ports {
+		if port != p && !port.closed {
+			port.queue = append(port.queue, append([]byte(nil), frame...))
+		}
+	}
+	return nil
+}
+
+func (p *SwitchPort) Receive() ([]byte, bool) {
+	if len(p.queue) == 0 {
+		return nil, false
+	}
+	frame := p.queue[0]
+	p.queue = p.queue[1:]
+	return frame, true
+}
+
+func (p *SwitchPort) Close() error {
+	p.closed = true
+	p.queue = nil
+	return nil
+}
+
+// UnixDatagramTransport sends and receives frames as unix datagrams
+type UnixDatagramTransport struct {
+	conn   *net.UnixConn
+	remote *net.UnixAddr
+	frames chan []byte
+}
+
+// ListenUnixDatagram binds to the socket path local and sends every frame to the socket path remote
+func ListenUnixDatagram(local, remote string) (*UnixDatagramTransport, error) {
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: local, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	t := &UnixDatagramTransport{
+		conn:   conn,
+		remote: &net.UnixAddr{Name: remote, Net: "unixgram"},
+		frames: make(chan []byte, 64),
+	}
+	go t.readLoop()
+	return t, nil
+}
+
+//INFO: the vm polls without blocking, so datagrams are read in the background and queued
+func (t *UnixDatagramTransport) readLoop() {
+	buffer := make([]byte, 0x10000)
+	for {
+		n, err := t.conn.Read(buffer)
+		if err != nil {
+			close(t.frames)
+			return
+		}
+		select {
+		case t.frames <- append([]byte(nil), buffer[:n]...):
+		default: // queue full, drop the frame
+		}
+	}
+}
+
+func (t *UnixDatagramTransport) Send(frame []byte) error {
+	_, err := t.conn.WriteToUnix(frame, t.remote)
+	return err
+}
+
+func (t *UnixDatagramTransport) Receive() ([]byte, bool) {
+	select {
+	case frame, ok := <-t.frames:
+		return frame, ok
+	default:
+		return nil, false
+	}
+}
+
+func (t *UnixDatagramTransport) Close() error {
+	return t.conn.Close()
+}
+
+// PcapTransport writes every sent frame to a pcap capture, it never receives anything
+type PcapTransport struct {
+	w   io.Writer
+	now func() time.Time
+}
+
+// pcap link type for frames without a standard link layer (LINKTYPE_USER0)
+const pcapLinkTypeUser0 = 147
+
+// NewPcapTransport writes the pcap header to w, now stamps the frames (time.Now when nil)
+func NewPcapTransport(w io.Writer, now func() time.Time) (*PcapTransport, error) {
+	if now == nil {
+		now = time.Now
+	}
+	header := []any{
+		uint32(0xA1B2C3D4), uint16(2), uint16(4), // magic, version 2.4
+		int32(0), uint32(0), uint32(0xFFFF), uint32(pcapLinkTypeUser0),
+	}
+	for _, field := range header {
+		if err := binary.Write(w, binary.LittleEndian, field); err != nil {
+			return nil, err
+		}
+	}
+	return &PcapTransport{w: w, now: now}, nil
+}
+
+func (t *PcapTransport) Send(frame []byte) error {
+	stamp := t.now()
+	record := []any{
+		uint32(stamp.Unix()), uint32(stamp.Nanosecond() / 1000),
+		uint32(len(frame)), uint32(len(frame)),
+	}
+	for _, field := range record {
+		if err := binary.Write(t.w, binary.LittleEndian, field); err != nil {
+			return err
+		}
+	}
+	_, err := t.w.Write(frame)
+	return err
+}
+
+func (t *PcapTransport) Receive() ([]byte, bool) {
+	return nil, false
+}
+
+func (t *PcapTransport) Close() error {
+	if closer, ok := t.w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
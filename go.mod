@@ -2,4 +2,4 @@ module github.com/martbul
 
 go 1.24.1
 
-require github.com/alecthomas/participle/v2 v2.1.4 // indirect
+require github.com/alecthomas/participle/v2 v2.1.4
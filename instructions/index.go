@@ -5,15 +5,18 @@ package instructions
 
 var InstructionByName map[string]MetaData
 var InstructionByOpcode map[uint8]MetaData
+var InstructionsByMnemonic map[string][]MetaData
 
 func init() {
-	// Initialize both maps
+	// Initialize the maps
 	InstructionByName = make(map[string]MetaData)
 	InstructionByOpcode = make(map[uint8]MetaData)
+	InstructionsByMnemonic = make(map[string][]MetaData)
 
 	for _, inst := range Instructions {
 		InstructionByName[inst.Instruction] = inst
 		InstructionByOpcode[inst.Opcode] = inst
+		InstructionsByMnemonic[inst.Mnemonic] = append(InstructionsByMnemonic[inst.Mnemonic], inst)
 	}
 }
 
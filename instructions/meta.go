@@ -29,8 +29,8 @@ const (
 	SizeSingleReg InstructionSize = 2
 	SizeSingleLit InstructionSize = 3
 	SizeRegReg    InstructionSize = 3
-	SizeRegMem    InstructionSize = 3
-	SizeMemReg    InstructionSize = 3
+	SizeRegMem    InstructionSize = 4
+	SizeMemReg    InstructionSize = 4
 	SizeRegLit    InstructionSize = 4
 	SizeRegLit8   InstructionSize = 3
 	SizeRegPtrReg InstructionSize = 3
@@ -0,0 +1,60 @@
+package instructions
+
+//INFO: The operands each instruction type takes, in the order they are written in assembly.
+// The assembler picks the opcode whose operand kinds match what was written, so a new
+// entry in Instructions is assemblable as soon as its Type is listed here.
+
+// OperandKind is the kind of a single instruction operand
+type OperandKind int
+
+const (
+	OperandRegister        OperandKind = iota // r1
+	OperandRegisterPointer                    // &r1
+	OperandAddress                            // &0050, &[expression]
+	OperandLiteral                            // $0050, !name, [expression]
+)
+
+func (k OperandKind) String() string {
+	switch k {
+	case OperandRegister:
+		return "register"
+	case OperandRegisterPointer:
+		return "register pointer"
+	case OperandAddress:
+		return "address"
+	case OperandLiteral:
+		return "literal"
+	}
+	return "unknown"
+}
+
+var Operands = map[InstructionType][]OperandKind{
+	LitReg:    {OperandLiteral, OperandRegister},
+	RegLit:    {OperandRegister, OperandLiteral},
+	RegLit8:   {OperandRegister, OperandLiteral},
+	RegReg:    {OperandRegister, OperandRegister},
+	RegMem:    {OperandRegister, OperandAddress},
+	MemReg:    {OperandAddress, OperandRegister},
+	LitMem:    {OperandLiteral, OperandAddress},
+	RegPtrReg: {OperandRegisterPointer, OperandRegister},
+	LitOffReg: {OperandLiteral, OperandRegisterPointer, OperandRegister},
+	NoArgs:    {},
+	SingleReg: {OperandRegister},
+	SingleLit: {OperandLiteral},
+}
+
+// OperandSize is the number of bytes an operand takes in machine code
+func OperandSize(t InstructionType, kind OperandKind) int {
+	switch {
+	case kind == OperandRegister || kind == OperandRegisterPointer:
+		return 1
+	case t == RegLit8:
+		return 1
+	}
+	return 2
+}
+
+// GetInstructionsByMnemonic returns every opcode written with the mnemonic
+func GetInstructionsByMnemonic(mnemonic string) []MetaData {
+	return InstructionsByMnemonic[mnemonic]
+}
@@ -0,0 +1,182 @@
+package machine
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/martbul/devices"
+	"github.com/martbul/memory"
+	memorymapper "github.com/martbul/memoryMapper"
+)
+
+//INFO: The device types every machine description can use. Params are listed next to every type.
+
+func init() {
+	// ram: size defaults to the mapped range
+	Register("ram", func(ctx *Context) (memorymapper.MemoryDevice, int, error) {
+		size := ctx.Params.Int("size", ctx.Size())
+		if size <= 0 {
+			return nil, 0, fmt.Errorf("ram needs an end address or a size")
+		}
+		return memory.CreateMemory(size), size, nil
+	})
+
+	Register("screen", func(ctx *Context) (memorymapper.MemoryDevice, int, error) {
+		return devices.CreateScreenDevice(), 0x100, nil
+	})
+
+	// timer: channels (1)
+	Register("timer", func(ctx *Context) (memorymapper.MemoryDevice, int, error) {
+		channels := ctx.Params.Int("channels", 1)
+		if channels < 1 || channels > 16 {
+			return nil, 0, fmt.Errorf("timer channels must be between 1 and 16")
+		}
+		timer := devices.CreateTimerDevice(channels, ctx.CPU.RequestInterupt, ctx.Spec.Interrupt)
+		return timer, timer.Size(), nil
+	})
+
+	// rtc: fixed (rfc3339 time, host clock when empty)
+	Register("rtc", func(ctx *Context) (memorymapper.MemoryDevice, int, error) {
+		fixed := ctx.Params.String("fixed", "")
+		if fixed == "" {
+			return devices.CreateRTCDevice(), devices.RTCSize, nil
+		}
+		t, err := time.Parse(time.RFC3339, fixed)
+		if err != nil {
+			return nil, 0, fmt.Errorf("rtc fixed time: %w", err)
+		}
+		return devices.CreateFixedRTCDevice(t), devices.RTCSize, nil
+	})
+
+	// block: image (path), mode ("rw", "ro" or "cow")
+	Register("block", func(ctx *Context) (memorymapper.MemoryDevice, int, error) {
+		image := ctx.Params.String("image", "")
+		modes := map[string]devices.BlockMode{
+			"rw":  devices.BlockReadWrite,
+			"ro":  devices.BlockReadOnly,
+			"cow": devices.BlockCopyOnWrite,
+		}
+		mode, ok := modes[ctx.Params.String("mode", "rw")]
+		if !ok {
+			return nil, 0, fmt.Errorf(`block mode must be "rw", "ro" or "cow"`)
+		}
+		if image == "" {
+			return nil, 0, fmt.Errorf("block needs an image")
+		}
+		device, err := devices.OpenBlockDevice(ctx.Path(image), mode, ctx.Memory)
+		return device, devices.BlockSize, err
+	})
+
+	// dma: unitsPerCycle (4)
+	Register("dma", func(ctx *Context) (memorymapper.MemoryDevice, int, error) {
+		units := ctx.Params.Int("unitsPerCycle", 4)
+		return devices.CreateDMADevice(ctx.Memory, units, ctx.CPU.RequestInterupt, ctx.Spec.Interrupt), devices.DMASize, nil
+	})
+
+	// semihost: dir (".")
+	Register("semihost", func(ctx *Context) (memorymapper.MemoryDevice, int, error) {
+		device, err := devices.CreateSemihostDevice(ctx.Memory, ctx.Path(ctx.Params.String("dir", ".")), ctx.Options.Console)
+		return device, devices.SemihostSize, err
+	})
+
+	// sound: sampleRate (8000), clockRate (1000000)
+	Register("sound", func(ctx *Context) (memorymapper.MemoryDevice, int, error) {
+		sound, err := devices.CreateSoundDevice(ctx.Params.Int("sampleRate", 8000), ctx.Params.Int("clockRate", 1000000))
+		if err != nil {
+			return nil, 0, err
+		}
+		return sound, devices.SoundSize, nil
+	})
+
+	// graphics: cyclesPerFrame (10000)
+	Register("graphics", func(ctx *Context) (memorymapper.MemoryDevice, int, error) {
+		cycles := ctx.Params.Int("cyclesPerFrame", 10000)
+		return devices.CreateGraphicsDevice(cycles, ctx.CPU.RequestInterupt, ctx.Spec.Interrupt), devices.GraphicsSize, nil
+	})
+
+	// random: seed (seeded from the host when missing)
+	Register("random", func(ctx *Context) (memorymapper.MemoryDevice, int, error) {
+		if _, ok := ctx.Params.values["seed"]; !ok {
+			return devices.CreateHostRandomDevice(), devices.RandomSize, nil
+		}
+		return devices.CreateRandomDevice(ctx.Params.Uint64("seed", 0)), devices.RandomSize, nil
+	})
+
+	// mailbox: id. Needs Options.MailboxHub
+	Register("mailbox", func(ctx *Context) (memorymapper.MemoryDevice, int, error) {
+		id := ctx.Params.Int("id", -1)
+		if id < 0 || id > 0xffff {
+			return nil, 0, fmt.Errorf("mailbox needs an id between 0 and 65535")
+		}
+		if ctx.Options.MailboxHub == nil {
+			return nil, 0, fmt.Errorf("mailbox needs Options.MailboxHub")
+		}
+		mailbox, err := ctx.Options.MailboxHub.CreateEndpoint(uint16(id), ctx.CPU.RequestInterupt, ctx.Spec.Interrupt)
+		if err != nil {
+			return nil, 0, err
+		}
+		return mailbox, devices.MailboxSize, nil
+	})
+
+	// nic: mac, transport ("switch", "unix" or "pcap"), local/remote (unix socket paths), file (pcap)
+	Register("nic", func(ctx *Context) (memorymapper.MemoryDevice, int, error) {
+		mac := ctx.Params.Int("mac", 0)
+		if mac < 0 || mac > 0xffff {
+			return nil, 0, fmt.Errorf("nic mac must be between 0 and 65535")
+		}
+		transportName := ctx.Params.String("transport", "switch")
+		local := ctx.Params.String("local", "")
+		remote := ctx.Params.String("remote", "")
+		file := ctx.Params.String("file", "")
+
+		var transport devices.PacketTransport
+		switch transportName {
+		case "switch":
+			if ctx.Options.PacketSwitch == nil {
+				return nil, 0, fmt.Errorf("nic switch transport needs Options.PacketSwitch")
+			}
+			transport = ctx.Options.PacketSwitch.Port()
+		case "unix":
+			t, err := devices.ListenUnixDatagram(ctx.Path(local), ctx.Path(remote))
+			if err != nil {
+				return nil, 0, err
+			}
+			transport = t
+		case "pcap":
+			f, err := os.Create(ctx.Path(file))
+			if err != nil {
+				return nil, 0, err
+			}
+			t, err := devices.NewPcapTransport(f, nil)
+			if err != nil {
+				f.Close()
+				return nil, 0, err
+			}
+			transport = t
+		default:
+			return nil, 0, fmt.Errorf(`nic transport must be "switch", "unix" or "pcap"`)
+		}
+		return devices.CreateNICDevice(ctx.Memory, transport, uint16(mac), ctx.CPU.RequestInterupt, ctx.Spec.Interrupt), devices.NICSize, nil
+	})
+
+	// system: ram (name of the ram device cleared by a full reset)
+	Register("system", func(ctx *Context) (memorymapper.MemoryDevice, int, error) {
+		var ram *memory.DataView
+		if name := ctx.Params.String("ram", ""); name != "" {
+			device, ok := ctx.Device(name)
+			if ram, ok = device.(*memory.DataView); !ok {
+				return nil, 0, fmt.Errorf("system ram %q is not a ram device declared before it", name)
+			}
+		}
+		return devices.CreateSystemDevice(ctx.CPU, ram), devices.SystemSize, nil
+	})
+
+	Register("debug", func(ctx *Context) (memorymapper.MemoryDevice, int, error) {
+		return devices.CreateDebugDevice(ctx.Options.Console, ctx.CPU), devices.DebugSize, nil
+	})
+
+	Register("gpio", func(ctx *Context) (memorymapper.MemoryDevice, int, error) {
+		return devices.CreateGPIODevice(ctx.CPU.GetCycles, ctx.CPU.RequestInterupt, ctx.Spec.Interrupt), devices.GPIOSize, nil
+	})
+}
@@ -0,0 +1,100 @@
+package machine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+//INFO: A machine description lists the devices (with their parameters, address range, remap flag and interupt line)
+// and the program image. Example:
+//
+//	{
+//	  "devices": [
+//	    { "name": "ram",    "type": "ram",    "start": 0,        "end": "0xffff" },
+//	    { "name": "screen", "type": "screen", "start": "0x3000", "end": "0x30ff" },
+//	    { "name": "timer",  "type": "timer",  "start": "0x3100", "interrupt": 1, "params": { "channels": 2 } }
+//	  ],
+//	  "program": { "image": "program.bin", "address": 0 }
+//	}
+//
+// Devices are mapped in order, a later device wins where two ranges overlap (like MemoryMapper.Map).
+// Addresses can be json numbers or strings in decimal or 0x hex.
+
+// Address is a 16 bit address that can be written as a number or as a "0x..." string
+type Address int
+
+func (a *Address) UnmarshalJSON(data []byte) error {
+	var number int
+	if err := json.Unmarshal(data, &number); err == nil {
+		*a = Address(number)
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("address must be a number or a string")
+	}
+	value, err := strconv.ParseInt(strings.TrimSpace(text), 0, 32)
+	if err != nil {
+		return fmt.Errorf("invalid address %q", text)
+	}
+	*a = Address(value)
+	return nil
+}
+
+type Config struct {
+	// InterruptVector is the address of the interupt vector table, 0xFFFE when nil
+	InterruptVector *Address     `json:"interruptVector,omitempty"`
+	Devices         []DeviceSpec `json:"devices"`
+	Program         *ProgramSpec `json:"program,omitempty"`
+
+	// Dir is the directory relative file names are resolved against, set by Load
+	Dir string `json:"-"`
+}
+
+type DeviceSpec struct {
+	Name      string         `json:"name"`
+	Type      string         `json:"type"`
+	Start     Address        `json:"start"`
+	End       *Address       `json:"end,omitempty"` // defaults to start + the device's size - 1
+	Remap     *bool          `json:"remap,omitempty"`
+	Interrupt uint16         `json:"interrupt,omitempty"`
+	Params    map[string]any `json:"params,omitempty"`
+}
+
+// ProgramSpec is the image loaded into memory before the cpu starts
+type ProgramSpec struct {
+	Image   string  `json:"image"`
+	Address Address `json:"address"`
+}
+
+// Parse decodes a json machine description
+func Parse(data []byte) (Config, error) {
+	var config Config
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	decoder.UseNumber() // params keep their exact digits, see Params.Int
+	if err := decoder.Decode(&config); err != nil {
+		return Config{}, fmt.Errorf("machine config: %w", err)
+	}
+	return config, nil
+}
+
+// LoadConfig reads a machine description, relative file names in it are resolved against its directory
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("machine config: %w", err)
+	}
+	config, err := Parse(data)
+	if err != nil {
+		return Config{}, err
+	}
+	config.Dir = filepath.Dir(path)
+	return config, nil
+}
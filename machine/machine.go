@@ -0,0 +1,180 @@
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/martbul/cpu"
+	memorymapper "github.com/martbul/memoryMapper"
+)
+
+// Machine is a cpu with its memory mapper and the devices of a machine description
+type Machine struct {
+	CPU     *cpu.CPU
+	Memory  *memorymapper.MemoryMapper
+	Devices map[string]memorymapper.MemoryDevice
+	order   []string
+}
+
+// Load reads a machine description and builds it
+func Load(path string, options *Options) (*Machine, error) {
+	config, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return Build(config, options)
+}
+
+// Build validates the whole description and creates the machine. All problems are reported together
+func Build(config Config, options *Options) (*Machine, error) {
+	if options == nil {
+		options = &Options{}
+	}
+	if errs := validate(config); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	memory := memorymapper.NewMemoryMapper()
+	vector := []int{}
+	if config.InterruptVector != nil {
+		vector = append(vector, int(*config.InterruptVector))
+	}
+	m := &Machine{
+		CPU:     cpu.NewCPU(memory, vector...),
+		Memory:  memory,
+		Devices: make(map[string]memorymapper.MemoryDevice),
+	}
+
+	var errs []error
+	for _, spec := range config.Devices {
+		if err := m.addDevice(spec, config.Dir, options); err != nil {
+			errs = append(errs, fmt.Errorf("device %q: %w", spec.Name, err))
+		}
+	}
+	if len(errs) == 0 && config.Program != nil {
+		if err := m.loadProgram(config.Program, config.Dir); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		m.Close()
+		return nil, errors.Join(errs...)
+	}
+	return m, nil
+}
+
+// validate checks everything that can be checked without creating devices
+func validate(config Config) []error {
+	var errs []error
+	if len(config.Devices) == 0 {
+		errs = append(errs, fmt.Errorf("no devices"))
+	}
+	if config.InterruptVector != nil && (*config.InterruptVector < 0 || *config.InterruptVector > 0xffff) {
+		errs = append(errs, fmt.Errorf("interrupt vector 0x%X outside of the address space", int(*config.InterruptVector)))
+	}
+
+	names := make(map[string]bool)
+	for i, spec := range config.Devices {
+		prefix := fmt.Sprintf("device %d (%q)", i, spec.Name)
+		if spec.Name == "" {
+			errs = append(errs, fmt.Errorf("%s: missing name", prefix))
+		} else if names[spec.Name] {
+			errs = append(errs, fmt.Errorf("%s: duplicate name", prefix))
+		}
+		names[spec.Name] = true
+
+		if _, ok := registry[spec.Type]; !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown device type %q (known: %v)", prefix, spec.Type, Types()))
+		}
+		if spec.Start < 0 || spec.Start > 0xffff {
+			errs = append(errs, fmt.Errorf("%s: start 0x%X outside of the address space", prefix, int(spec.Start)))
+		}
+		if spec.End != nil && (*spec.End < spec.Start || *spec.End > 0xffff) {
+			errs = append(errs, fmt.Errorf("%s: end 0x%X must be between start and 0xFFFF", prefix, int(*spec.End)))
+		}
+	}
+	return errs
+}
+
+func (m *Machine) addDevice(spec DeviceSpec, dir string, options *Options) error {
+	ctx := &Context{
+		Spec:    spec,
+		Params:  newParams(spec.Params),
+		CPU:     m.CPU,
+		Memory:  m.Memory,
+		Options: options,
+		dir:     dir,
+		devices: m.Devices,
+	}
+
+	device, size, err := registry[spec.Type](ctx)
+	if err != nil {
+		return err
+	}
+	m.Devices[spec.Name] = device
+	m.order = append(m.order, spec.Name)
+	if errs := ctx.Params.check(); len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	end := int(spec.Start) + size - 1
+	if spec.End != nil {
+		end = int(*spec.End)
+	} else if size == 0 {
+		return fmt.Errorf("missing end address")
+	}
+	if end > 0xffff {
+		return fmt.Errorf("device of 0x%X bytes at 0x%04X does not fit in the address space", size, int(spec.Start))
+	}
+
+	remap := true
+	if spec.Remap != nil {
+		remap = *spec.Remap
+	}
+	m.Memory.Map(device, int(spec.Start), end, remap)
+	return nil
+}
+
+func (m *Machine) loadProgram(program *ProgramSpec, dir string) error {
+	ctx := &Context{dir: dir}
+	image, err := os.ReadFile(ctx.Path(program.Image))
+	if err != nil {
+		return fmt.Errorf("program: %w", err)
+	}
+	if int(program.Address)+len(image) > 0x10000 {
+		return fmt.Errorf("program: image of %d bytes does not fit at 0x%04X", len(image), int(program.Address))
+	}
+	for i, value := range image {
+		if err := m.Memory.SetUint8(int(program.Address)+i, value); err != nil {
+			return fmt.Errorf("program: %w", err)
+		}
+	}
+	m.CPU.SetEntryPoint(uint16(program.Address))
+	return nil
+}
+
+// Reset resets the cpu and every device, like the system controller's RESET register.
+// The cpu starts again at the address the program was loaded at
+func (m *Machine) Reset() {
+	m.CPU.Reset()
+}
+
+// Run runs the cpu until it halts
+func (m *Machine) Run() {
+	m.CPU.Run()
+}
+
+// Close releases the host resources of every device that holds some (files, sockets, ...)
+func (m *Machine) Close() error {
+	var errs []error
+	for i := len(m.order) - 1; i >= 0; i-- {
+		if closer, ok := m.Devices[m.order[i]].(memorymapper.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,102 @@
+package machine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/martbul/devices"
+	"github.com/martbul/instructions"
+	"github.com/martbul/registers"
+)
+
+// writeImage writes a program image into a temporary directory and returns the directory
+func writeImage(t *testing.T, image []byte) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "program.bin"), image, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestResetRestartsAtProgramAddress(t *testing.T) {
+	dir := writeImage(t, []byte{instructions.MOV_LIT_REG, 0x12, 0x34, byte(registers.Map["r1"]), instructions.HLT})
+	config, err := Parse([]byte(`{
+		"devices": [
+			{ "name": "ram", "type": "ram", "start": 0, "end": "0xffff" },
+			{ "name": "sys", "type": "system", "start": "0xff00" }
+		],
+		"program": { "image": "program.bin", "address": "0x0200" }
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Dir = dir
+	m, err := Build(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	if ip := m.CPU.GetRegister("ip"); ip != 0x0200 {
+		t.Fatalf("ip = %04X after loading, want 0200", ip)
+	}
+	m.Run()
+	m.Reset()
+	if ip := m.CPU.GetRegister("ip"); ip != 0x0200 {
+		t.Errorf("ip = %04X after Machine.Reset, want 0200", ip)
+	}
+
+	m.Run()
+	m.Devices["sys"].SetUint16(0, devices.SystemResetCPU)
+	if ip := m.CPU.GetRegister("ip"); ip != 0x0200 {
+		t.Errorf("ip = %04X after a RESET from the system controller, want 0200", ip)
+	}
+	m.Run()
+	if r1 := m.CPU.GetRegister("r1"); r1 != 0x1234 {
+		t.Errorf("r1 = %04X after running again, want 1234", r1)
+	}
+}
+
+func TestBuildReportsEveryError(t *testing.T) {
+	config, err := Parse([]byte(`{"devices": [
+		{ "name": "a", "type": "nope", "start": 0 },
+		{ "name": "a", "type": "ram", "start": "0x10000" }
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Build(config, nil)
+	if err == nil {
+		t.Fatal("Build did not fail")
+	}
+	for _, want := range []string{"unknown device type", "duplicate name", "outside of the address space"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestBuildFreesMailboxIDs(t *testing.T) {
+	hub := devices.NewMailboxHub()
+	mailbox := `{ "name": "mb", "type": "mailbox", "start": "0x3000", "params": { "id": 1 } }`
+	duplicate := mailbox + `, { "name": "mb2", "type": "mailbox", "start": "0x3010", "params": { "id": 1 } }`
+
+	for i := 0; i < 2; i++ {
+		_, err := buildJSON(t, duplicate, &Options{MailboxHub: hub})
+		if err == nil || !strings.Contains(err.Error(), "already in use") {
+			t.Fatalf("attempt %d: error = %v, want the id to be in use by mb", i, err)
+		}
+	}
+
+	m, err := buildJSON(t, mailbox, &Options{MailboxHub: hub})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+	if _, err := buildJSON(t, mailbox, &Options{MailboxHub: hub}); err != nil {
+		t.Errorf("id 1 is not free after Close: %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package machine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/martbul/cpu"
+	"github.com/martbul/devices"
+	memorymapper "github.com/martbul/memoryMapper"
+)
+
+// Factory creates a device from its description. It returns the device and the number of bytes it occupies
+// in the address space (0 when the description has to give an end address)
+type Factory func(ctx *Context) (memorymapper.MemoryDevice, int, error)
+
+var registry = map[string]Factory{}
+
+// Register makes a device type available to machine descriptions, third party devices call it from an init function
+func Register(typeName string, factory Factory) {
+	if _, exists := registry[typeName]; exists {
+		panic(fmt.Sprintf("machine: device type %q registered twice", typeName))
+	}
+	registry[typeName] = factory
+}
+
+// Types returns the names of all registered device types
+func Types() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Options carries the objects that are shared between machines or supplied by go code
+type Options struct {
+	MailboxHub   *devices.MailboxHub   // used by "mailbox" devices
+	PacketSwitch *devices.PacketSwitch // used by "nic" devices with the "switch" transport
+	Console      io.Writer             // used by "semihost" and "debug" devices, os.Stdout when nil
+}
+
+// Context is what a Factory gets to build its device
+type Context struct {
+	Spec    DeviceSpec
+	Params  *Params
+	CPU     *cpu.CPU
+	Memory  *memorymapper.MemoryMapper
+	Options *Options
+
+	dir     string
+	devices map[string]memorymapper.MemoryDevice
+}
+
+// Path resolves a file name from the description against the description's directory
+func (c *Context) Path(name string) string {
+	if filepath.IsAbs(name) || c.dir == "" {
+		return name
+	}
+	return filepath.Join(c.dir, name)
+}
+
+// Device returns a device that was declared earlier in the description
+func (c *Context) Device(name string) (memorymapper.MemoryDevice, bool) {
+	device, ok := c.devices[name]
+	return device, ok
+}
+
+// Size returns the number of bytes mapped for the device, 0 when the description has no end address
+func (c *Context) Size() int {
+	if c.Spec.End == nil {
+		return 0
+	}
+	return int(*c.Spec.End) - int(c.Spec.Start) + 1
+}
+
+// Params gives typed access to the "params" object of a device description.
+// Conversion errors and unknown keys are collected and reported when the machine is built
+type Params struct {
+	values map[string]any
+	used   map[string]bool
+	errs   []error
+}
+
+func newParams(values map[string]any) *Params {
+	return &Params{values: values, used: make(map[string]bool)}
+}
+
+func (p *Params) Int(key string, defaultValue int) int {
+	p.used[key] = true
+	value, ok := p.values[key]
+	if !ok {
+		return defaultValue
+	}
+	number, err := strconv.ParseInt(integerText(value), 10, strconv.IntSize)
+	if err != nil {
+		p.errs = append(p.errs, paramIntegerError(key, "an integer", err))
+		return defaultValue
+	}
+	return int(number)
+}
+
+// Uint64 reads a non-negative integer that may use all 64 bits, like a random seed
+func (p *Params) Uint64(key string, defaultValue uint64) uint64 {
+	p.used[key] = true
+	value, ok := p.values[key]
+	if !ok {
+		return defaultValue
+	}
+	number, err := strconv.ParseUint(integerText(value), 10, 64)
+	if err != nil {
+		p.errs = append(p.errs, paramIntegerError(key, "a non-negative integer", err))
+		return defaultValue
+	}
+	return number
+}
+
+// integerText returns the decimal digits of an integer param. Parse keeps json numbers as
+// json.Number so that they don't lose precision, params set from go can be any integer type
+// or an integral float64. Anything else returns "" which fails to parse.
+func integerText(value any) string {
+	switch number := value.(type) {
+	case json.Number:
+		return number.String()
+	case float64:
+		if number == math.Trunc(number) && !math.IsInf(number, 0) {
+			return strconv.FormatFloat(number, 'f', -1, 64)
+		}
+	case int:
+		return strconv.FormatInt(int64(number), 10)
+	case int64:
+		return strconv.FormatInt(number, 10)
+	case uint64:
+		return strconv.FormatUint(number, 10)
+	}
+	return ""
+}
+
+func paramIntegerError(key, kind string, err error) error {
+	if errors.Is(err, strconv.ErrRange) {
+		return fmt.Errorf("param %q is out of range", key)
+	}
+	return fmt.Errorf("param %q must be %s", key, kind)
+}
+
+func (p *Params) String(key string, defaultValue string) string {
+	p.used[key] = true
+	value, ok := p.values[key]
+	if !ok {
+		return defaultValue
+	}
+	text, ok := value.(string)
+	if !ok {
+		p.errs = append(p.errs, fmt.Errorf("param %q must be a string", key))
+		return defaultValue
+	}
+	return text
+}
+
+func (p *Params) Bool(key string, defaultValue bool) bool {
+	p.used[key] = true
+	value, ok := p.values[key]
+	if !ok {
+		return defaultValue
+	}
+	flag, ok := value.(bool)
+	if !ok {
+		p.errs = append(p.errs, fmt.Errorf("param %q must be a boolean", key))
+		return defaultValue
+	}
+	return flag
+}
+
+// check returns the collected errors and an error for every param the factory never asked for
+func (p *Params) check() []error {
+	errs := p.errs
+	for key := range p.values {
+		if !p.used[key] {
+			errs = append(errs, fmt.Errorf("unknown param %q", key))
+		}
+	}
+	return errs
+}
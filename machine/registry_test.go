@@ -0,0 +1,86 @@
+package machine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/martbul/devices"
+)
+
+// buildJSON builds a machine with ram and the given device json
+func buildJSON(t *testing.T, device string, options *Options) (*Machine, error) {
+	t.Helper()
+	config, err := Parse([]byte(`{"devices": [
+		{ "name": "ram", "type": "ram", "start": 0, "end": "0x0fff" },
+		` + device + `
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Build(config, options)
+}
+
+func TestParamsSeed(t *testing.T) {
+	tests := []struct {
+		seed string
+		want uint64
+		err  string
+	}{
+		{"42", 42, ""},
+		{"9007199254740993", 9007199254740993, ""}, // 2^53 + 1, a float64 rounds it
+		{"18446744073709551615", 18446744073709551615, ""},
+		{"18446744073709551616", 0, "out of range"},
+		{"-1", 0, "non-negative integer"},
+		{"1.5", 0, "non-negative integer"},
+		{`"42"`, 0, "non-negative integer"},
+	}
+	for _, test := range tests {
+		t.Run(test.seed, func(t *testing.T) {
+			m, err := buildJSON(t, `{ "name": "rng", "type": "random", "start": "0x3000", "params": { "seed": `+test.seed+` } }`, nil)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error = %v, want one containing %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if seed := m.Devices["rng"].(*devices.RandomDevice).Seed(); seed != test.want {
+				t.Errorf("seed = %d, want %d", seed, test.want)
+			}
+		})
+	}
+}
+
+func TestParamsRange(t *testing.T) {
+	tests := []struct {
+		name, device, err string
+	}{
+		{"mac too big", `{ "name": "nic", "type": "nic", "start": "0x3000", "params": { "mac": 65536 } }`, "mac"},
+		{"negative mac", `{ "name": "nic", "type": "nic", "start": "0x3000", "params": { "mac": -1 } }`, "mac"},
+		{"mailbox id too big", `{ "name": "mb", "type": "mailbox", "start": "0x3000", "params": { "id": 70000 } }`, "id"},
+		{"int overflow", `{ "name": "dma", "type": "dma", "start": "0x3000", "params": { "unitsPerCycle": 99999999999999999999 } }`, "out of range"},
+		{"fraction", `{ "name": "dma", "type": "dma", "start": "0x3000", "params": { "unitsPerCycle": 2.5 } }`, "must be an integer"},
+		{"unknown param", `{ "name": "dma", "type": "dma", "start": "0x3000", "params": { "speed": 2 } }`, "unknown param"},
+	}
+	options := &Options{MailboxHub: devices.NewMailboxHub(), PacketSwitch: devices.NewPacketSwitch()}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := buildJSON(t, test.device, options)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("error = %v, want one containing %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestParamsFromGo(t *testing.T) {
+	params := newParams(map[string]any{"a": 3, "b": float64(4), "c": uint64(5), "d": 1.5})
+	if a, b, c := params.Int("a", 0), params.Int("b", 0), params.Uint64("c", 0); a != 3 || b != 4 || c != 5 {
+		t.Errorf("got %d %d %d, want 3 4 5", a, b, c)
+	}
+	if d := params.Int("d", 7); d != 7 || len(params.check()) != 1 {
+		t.Errorf("1.5 read as %d with errors %v, want the default and one error", d, params.check())
+	}
+}
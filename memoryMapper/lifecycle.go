@@ -0,0 +1,88 @@
+package memorymapper
+
+import "errors"
+
+//INFO: Optional interfaces a MemoryDevice can implement to take part in the machine's lifecycle.
+// Devices that only have the four read/write methods keep working, they are simply skipped.
+
+// Resetter is implemented by devices that return to their power on state when the machine resets
+type Resetter interface {
+	Reset()
+}
+
+// Ticker is implemented by devices that advance with the cpu's cycle counter
+type Ticker interface {
+	Tick(cycles int)
+}
+
+// Closer is implemented by devices that hold host resources (files, sockets, ...)
+type Closer interface {
+	Close() error
+}
+
+// LifecycleDevice is a device implementing every lifecycle hook
+type LifecycleDevice interface {
+	MemoryDevice
+	Resetter
+	Ticker
+	Closer
+}
+
+// Devices returns every mapped device once, in mapping order (a device mapped into several regions is listed once)
+func (m *MemoryMapper) Devices() []MemoryDevice {
+	var devices []MemoryDevice
+	m.eachDevice(func(device MemoryDevice) {
+		devices = append(devices, device)
+	})
+	return devices
+}
+
+// eachDevice calls fn for every mapped device once, in mapping order. It does not allocate, TickDevices runs after every instruction
+func (m *MemoryMapper) eachDevice(fn func(MemoryDevice)) {
+	//INFO: Regions are prepended by Map, so the oldest mapping is the last one
+	for i := len(m.Regions) - 1; i >= 0; i-- {
+		device := m.Regions[i].Device
+		mappedEarlier := false
+		for j := len(m.Regions) - 1; j > i; j-- {
+			if m.Regions[j].Device == device {
+				mappedEarlier = true
+				break
+			}
+		}
+		if !mappedEarlier {
+			fn(device)
+		}
+	}
+}
+
+// ResetDevices resets every mapped device that implements Resetter
+func (m *MemoryMapper) ResetDevices() {
+	m.eachDevice(func(device MemoryDevice) {
+		if resetter, ok := device.(Resetter); ok {
+			resetter.Reset()
+		}
+	})
+}
+
+// TickDevices advances every mapped device that implements Ticker
+func (m *MemoryMapper) TickDevices(cycles int) {
+	m.eachDevice(func(device MemoryDevice) {
+		if ticker, ok := device.(Ticker); ok {
+			ticker.Tick(cycles)
+		}
+	})
+}
+
+// CloseDevices closes every mapped device that implements Closer, in reverse mapping order
+func (m *MemoryMapper) CloseDevices() error {
+	devices := m.Devices()
+	var errs []error
+	for i := len(devices) - 1; i >= 0; i-- {
+		if closer, ok := devices[i].(Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
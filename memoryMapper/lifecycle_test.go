@@ -0,0 +1,91 @@
+package memorymapper
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// plainDevice only has the four read/write methods
+type plainDevice struct{}
+
+func (plainDevice) GetUint16(address int) uint16        { return 0 }
+func (plainDevice) GetUint8(address int) uint8          { return 0 }
+func (plainDevice) SetUint16(address int, value uint16) {}
+func (plainDevice) SetUint8(address int, value uint8)   {}
+
+// hookDevice records every lifecycle call into a shared log
+type hookDevice struct {
+	plainDevice
+	name     string
+	log      *[]string
+	closeErr error
+}
+
+func (d *hookDevice) Reset()          { *d.log = append(*d.log, "reset "+d.name) }
+func (d *hookDevice) Tick(cycles int) { *d.log = append(*d.log, "tick "+d.name) }
+func (d *hookDevice) Close() error {
+	*d.log = append(*d.log, "close "+d.name)
+	return d.closeErr
+}
+
+// tickOnly implements only one of the hooks
+type tickOnly struct {
+	plainDevice
+	cycles int
+}
+
+func (d *tickOnly) Tick(cycles int) { d.cycles += cycles }
+
+func TestLifecycleOrder(t *testing.T) {
+	var log []string
+	first := &hookDevice{name: "first", log: &log}
+	second := &hookDevice{name: "second", log: &log, closeErr: errors.New("second failed")}
+	third := &hookDevice{name: "third", log: &log, closeErr: errors.New("third failed")}
+	ticker := &tickOnly{}
+
+	m := NewMemoryMapper()
+	m.Map(first, 0x0000, 0x0fff)
+	m.Map(plainDevice{}, 0x1000, 0x1fff)
+	m.Map(second, 0x2000, 0x2fff)
+	m.Map(ticker, 0x3000, 0x3fff)
+	m.Map(second, 0x4000, 0x4fff) // mapped twice, hooked once
+	m.Map(third, 0x5000, 0x5fff)
+	unmap := m.Map(&hookDevice{name: "unmapped", log: &log}, 0x6000, 0x6fff)
+	unmap()
+
+	m.ResetDevices()
+	m.TickDevices(3)
+	err := m.CloseDevices()
+
+	want := []string{
+		"reset first", "reset second", "reset third",
+		"tick first", "tick second", "tick third",
+		"close third", "close second", "close first",
+	}
+	if !slices.Equal(log, want) {
+		t.Errorf("hooks ran as\n%v\nwant\n%v", log, want)
+	}
+	if ticker.cycles != 3 {
+		t.Errorf("a device with only Tick was ticked %d cycles, want 3", ticker.cycles)
+	}
+	if !errors.Is(err, second.closeErr) || !errors.Is(err, third.closeErr) {
+		t.Errorf("CloseDevices = %v, want both close errors", err)
+	}
+}
+
+func TestDevicesListsEachDeviceOnce(t *testing.T) {
+	a, b := &tickOnly{}, &tickOnly{}
+	m := NewMemoryMapper()
+	m.Map(a, 0x0000, 0x00ff)
+	m.Map(b, 0x0100, 0x01ff)
+	m.Map(a, 0x0200, 0x02ff)
+
+	devices := m.Devices()
+	if len(devices) != 2 || devices[0] != MemoryDevice(a) || devices[1] != MemoryDevice(b) {
+		t.Errorf("Devices = %v, want a then b", devices)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { m.TickDevices(1) }); allocs != 0 {
+		t.Errorf("TickDevices allocates %v times per call", allocs)
+	}
+}
@@ -0,0 +1,12 @@
+; Stack helpers shared by the example programs
+
+macro push2 a, b
+  psh a
+  psh b
+endm
+
+; pops in the reverse order, so "pop2 r1, r2" undoes "push2 r1, r2"
+macro pop2 a, b
+  pop b
+  pop a
+endm
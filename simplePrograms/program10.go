@@ -0,0 +1,55 @@
+package simpleprograms
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/martbul/instructions"
+	"github.com/martbul/machine"
+	"github.com/martbul/registers"
+)
+
+const timerMachine = `{
+	"devices": [
+		{ "name": "ram",   "type": "ram",   "start": 0,        "end": "0xffff" },
+		{ "name": "timer", "type": "timer", "start": "0x3100", "params": { "channels": 1 } }
+	]
+}`
+
+//INFO: The program (timer mapped at 0x3100, one channel -> STATUS is at 0x3108):
+
+// mov $0005, &3102   ;; RELOAD = 5
+// mov $0001, &3100   ;; CTRL = enable, one-shot
+// loop:
+//   mov &3108, acc
+//   jeq $0000, &[!loop]
+// hlt
+
+func SimpleProgram10() {
+	config, err := machine.Parse([]byte(timerMachine))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	vm, err := machine.Build(config, nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	defer vm.Close()
+
+	program := []byte{
+		instructions.MOV_LIT_MEM, 0x00, 0x05, 0x31, 0x02,
+		instructions.MOV_LIT_MEM, 0x00, 0x01, 0x31, 0x00,
+		// loop (address 0x000A)
+		instructions.MOV_MEM_REG, 0x31, 0x08, byte(registers.Map["acc"]),
+		instructions.JEQ_LIT, 0x00, 0x00, 0x00, 0x0A,
+		instructions.HLT,
+	}
+	for i, value := range program {
+		vm.Memory.SetUint8(i, value)
+	}
+
+	vm.Run()
+	fmt.Printf("timer expired after %d cycles\n", vm.CPU.GetCycles())
+}
@@ -0,0 +1,71 @@
+package simpleprograms
+
+import (
+	"fmt"
+
+	cpuPack "github.com/martbul/cpu"
+	"github.com/martbul/devices"
+	"github.com/martbul/instructions"
+	"github.com/martbul/memory"
+	memMapper "github.com/martbul/memoryMapper"
+	"github.com/martbul/registers"
+)
+
+//INFO: Two vms connected through a mailbox (mapped at 0x3000 in both)
+
+// vm 1:
+// mov $0002, &3002   ;; DEST = vm 2
+// mov $1234, &3004   ;; TX
+// hlt
+//
+// vm 2:
+// loop:
+//   mov &300A, acc     ;; STATUS
+//   jeq $0000, &[!loop]
+// mov &3006, r1        ;; RX
+// hlt
+
+func SimpleProgram11() {
+	hub := devices.NewMailboxHub()
+
+	sender, err := createMailboxVM(hub, 1, []byte{
+		instructions.MOV_LIT_MEM, 0x00, 0x02, 0x30, 0x02,
+		instructions.MOV_LIT_MEM, 0x12, 0x34, 0x30, 0x04,
+		instructions.HLT,
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	receiver, err := createMailboxVM(hub, 2, []byte{
+		instructions.MOV_MEM_REG, 0x30, 0x0A, byte(registers.Map["acc"]),
+		instructions.JEQ_LIT, 0x00, 0x00, 0x00, 0x00,
+		instructions.MOV_MEM_REG, 0x30, 0x06, byte(registers.Map["r1"]),
+		instructions.HLT,
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	scheduler := cpuPack.NewScheduler(cpuPack.Lockstep, receiver, sender)
+	scheduler.Run()
+
+	fmt.Printf("vm 2 received 0x%04X\n", receiver.GetRegister("r1"))
+}
+
+func createMailboxVM(hub *devices.MailboxHub, id uint16, program []byte) (*cpuPack.CPU, error) {
+	ram := memory.CreateMemory(256 * 256)
+	copy(ram.GetBuffer(), program)
+
+	memoryMapper := memMapper.NewMemoryMapper()
+	memoryMapper.Map(ram, 0, 0xffff)
+
+	cpu := cpuPack.NewCPU(memoryMapper)
+	mailbox, err := hub.CreateEndpoint(id, cpu.RequestInterupt, 0)
+	if err != nil {
+		return nil, err
+	}
+	memoryMapper.Map(mailbox, 0x3000, 0x3000+devices.MailboxSize-1, true)
+	return cpu, nil
+}
@@ -0,0 +1,44 @@
+package simpleprograms
+
+import (
+	"fmt"
+
+	"github.com/martbul/assembler"
+	cpuPack "github.com/martbul/cpu"
+	"github.com/martbul/memory"
+	memMapper "github.com/martbul/memoryMapper"
+)
+
+//INFO: Assembles a program written with a macro and a rept block and runs it, r1 ends up as 1+2+3+4+1 = 11
+
+const macroProgram = `macro add_to reg, value = 1   ; reg += value
+  add value, reg
+  mov acc, reg
+endm
+
+mov 0, r1
+rept 4, i
+  add_to r1, [i + 1]
+endr
+add_to r1
+hlt`
+
+func SimpleProgram14() {
+	result, err := assembler.Assemble(macroProgram)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	ram := memory.CreateMemory(0x10000)
+	memoryMapper := memMapper.NewMemoryMapper()
+	memoryMapper.Map(ram, 0, 0xffff)
+	if err := result.Load(ram); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	cpu := cpuPack.NewCPU(memoryMapper)
+	cpu.Run()
+	fmt.Printf("r1 = %d\n", cpu.GetRegister("r1"))
+}
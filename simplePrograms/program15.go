@@ -0,0 +1,45 @@
+package simpleprograms
+
+import (
+	"fmt"
+
+	"github.com/martbul/assembler"
+	cpuPack "github.com/martbul/cpu"
+	"github.com/martbul/memory"
+	memMapper "github.com/martbul/memoryMapper"
+)
+
+//INFO: Includes the stack macros from simplePrograms/asm (run from the repository root), saves r1 and r2, clobbers them and restores them
+
+const includeProgram = `include "stack.asm"
+
+mov 1, r1
+mov 2, r2
+push2 r1, r2
+mov 0, r1
+mov 0, r2
+pop2 r1, r2
+hlt`
+
+func SimpleProgram15() {
+	result, err := assembler.Assemble(includeProgram, assembler.Options{
+		Filename:     "program15.asm",
+		IncludePaths: []string{"simplePrograms/asm"},
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	ram := memory.CreateMemory(0x10000)
+	memoryMapper := memMapper.NewMemoryMapper()
+	memoryMapper.Map(ram, 0, 0xffff)
+	if err := result.Load(ram); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	cpu := cpuPack.NewCPU(memoryMapper)
+	cpu.Run()
+	fmt.Printf("r1 = %d, r2 = %d\n", cpu.GetRegister("r1"), cpu.GetRegister("r2"))
+}